@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"expvar"
 	"flag"
 	"fmt"
 	"io"
@@ -11,14 +12,21 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"regexp"
+	"strings"
 	"time"
 
 	httphandler "github.com/bojackodin/notes/internal/http/handler"
 	httpserver "github.com/bojackodin/notes/internal/http/server"
 	"github.com/bojackodin/notes/internal/log"
+	"github.com/bojackodin/notes/internal/notifier"
 	"github.com/bojackodin/notes/internal/repository"
 	"github.com/bojackodin/notes/internal/service"
-	"github.com/bojackodin/notes/internal/yandex/speller"
+	"github.com/bojackodin/notes/internal/signing"
+	"github.com/bojackodin/notes/internal/speller"
+	"github.com/bojackodin/notes/internal/speller/dictionary"
+	"github.com/bojackodin/notes/internal/throttle"
+	yandexspeller "github.com/bojackodin/notes/internal/yandex/speller"
 
 	"github.com/kelseyhightower/envconfig"
 	_ "github.com/lib/pq"
@@ -44,6 +52,9 @@ type config struct {
 			ReadTimeout     time.Duration `yaml:"read_timeout" split_words:"true"`
 			WriteTimeout    time.Duration `yaml:"write_timeout" split_words:"true"`
 			IdleTimeout     time.Duration `yaml:"idle_timeout" split_words:"true"`
+			// ClientIPHeader is set by a trusted reverse proxy, e.g. X-Real-IP.
+			// Of a list, the rightmost value is taken.
+			ClientIPHeader string `yaml:"client_ip_header" split_words:"true"`
 		} `yaml:"http"`
 	} `yaml:"server"`
 	Logger struct {
@@ -57,9 +68,121 @@ type config struct {
 		MaxIdleTime  time.Duration `yaml:"max_idle_time" split_words:"true"`
 	} `yaml:"postgres"`
 	JWT struct {
-		Secret   string        `yaml:"secret"`
-		TokenTTL time.Duration `yaml:"token_ttl" split_words:"true"`
+		// Secret is the HS256 key, used for tokens without a kid header.
+		Secret string `yaml:"secret"`
+		// SigningKey is the ID of the key new tokens are signed with, the
+		// secret if empty.
+		SigningKey string `yaml:"signing_key" split_words:"true"`
+		Keys       []struct {
+			ID             string `yaml:"id"`
+			Algorithm      string `yaml:"algorithm"`
+			PrivateKeyFile string `yaml:"private_key_file"`
+			// PublicKeyFile is used for retired keys that only verify tokens.
+			PublicKeyFile string `yaml:"public_key_file"`
+		} `yaml:"keys" ignored:"true"`
+		TokenTTL        time.Duration `yaml:"token_ttl" split_words:"true"`
+		RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" split_words:"true"`
 	} `yaml:"jwt"`
+	Policy struct {
+		Username struct {
+			MinLength int      `yaml:"min_length" split_words:"true"`
+			MaxLength int      `yaml:"max_length" split_words:"true"`
+			Pattern   string   `yaml:"pattern"`
+			Reserved  []string `yaml:"reserved"`
+		} `yaml:"username"`
+		Password struct {
+			MinLength     int    `yaml:"min_length" split_words:"true"`
+			MaxLength     int    `yaml:"max_length" split_words:"true"`
+			RequireUpper  bool   `yaml:"require_upper" split_words:"true"`
+			RequireLower  bool   `yaml:"require_lower" split_words:"true"`
+			RequireDigit  bool   `yaml:"require_digit" split_words:"true"`
+			RequireSymbol bool   `yaml:"require_symbol" split_words:"true"`
+			DenylistFile  string `yaml:"denylist_file" split_words:"true"`
+		} `yaml:"password"`
+	} `yaml:"policy"`
+	SignIn struct {
+		Username throttleConfig `yaml:"username"`
+		IP       throttleConfig `yaml:"ip"`
+	} `yaml:"sign_in" split_words:"true"`
+	TwoFactor struct {
+		Issuer       string        `yaml:"issuer"`
+		ChallengeTTL time.Duration `yaml:"challenge_ttl" split_words:"true"`
+	} `yaml:"two_factor" split_words:"true"`
+	PasswordReset struct {
+		TokenTTL time.Duration `yaml:"token_ttl" split_words:"true"`
+		Notifier string        `yaml:"notifier"`
+		File     string        `yaml:"file"`
+		// Every request counts as an attempt of the username and of the
+		// client IP.
+		Username throttleConfig `yaml:"username"`
+		IP       throttleConfig `yaml:"ip"`
+	} `yaml:"password_reset" split_words:"true"`
+	Notes struct {
+		RequireIfMatch bool `yaml:"require_if_match" split_words:"true"`
+	} `yaml:"notes"`
+	Trash struct {
+		Retention     time.Duration `yaml:"retention"`
+		PurgeInterval time.Duration `yaml:"purge_interval" split_words:"true"`
+	} `yaml:"trash"`
+	Speller struct {
+		Backend string `yaml:"backend"`
+		// Policy is the default spell policy of note writes.
+		Policy string `yaml:"policy"`
+		// Zero or missing Yandex.Speller settings keep the defaults.
+		Yandex struct {
+			URL            string        `yaml:"url"`
+			ConnectTimeout time.Duration `yaml:"connect_timeout" split_words:"true"`
+			Timeout        time.Duration `yaml:"timeout"`
+			Retries        int           `yaml:"retries"`
+			RetryBaseDelay time.Duration `yaml:"retry_base_delay" split_words:"true"`
+			RetryMaxDelay  time.Duration `yaml:"retry_max_delay" split_words:"true"`
+			// BreakerThreshold is the number of consecutive failed checks
+			// opening the circuit breaker.
+			BreakerThreshold int           `yaml:"breaker_threshold" split_words:"true"`
+			BreakerCooldown  time.Duration `yaml:"breaker_cooldown" split_words:"true"`
+			// FailOpen accepts notes unchecked while the service is
+			// unavailable instead of failing them with 503.
+			FailOpen bool `yaml:"fail_open" split_words:"true"`
+		} `yaml:"yandex"`
+		Cache struct {
+			// Size is the number of cached results, zero to disable
+			// caching.
+			Size int `yaml:"size"`
+			// TTL is how long results are kept, zero to keep them
+			// until evicted.
+			TTL time.Duration `yaml:"ttl"`
+		} `yaml:"cache"`
+		Dictionary struct {
+			// Files are Hunspell .dic files, read with the .aff files of
+			// the same name, or plain word lists.
+			Files []string `yaml:"files"`
+		} `yaml:"dictionary"`
+	} `yaml:"speller"`
+}
+
+type throttleConfig struct {
+	FreeAttempts     int           `yaml:"free_attempts" split_words:"true"`
+	BaseDelay        time.Duration `yaml:"base_delay" split_words:"true"`
+	MaxDelay         time.Duration `yaml:"max_delay" split_words:"true"`
+	LockoutThreshold int           `yaml:"lockout_threshold" split_words:"true"`
+	LockoutDuration  time.Duration `yaml:"lockout_duration" split_words:"true"`
+	Window           time.Duration `yaml:"window"`
+}
+
+// newLimiter returns nil, disabling throttling, if the window is not set.
+func (c throttleConfig) newLimiter() *throttle.Limiter {
+	if c.Window <= 0 {
+		return nil
+	}
+
+	return throttle.New(throttle.Config{
+		FreeAttempts:     c.FreeAttempts,
+		BaseDelay:        c.BaseDelay,
+		MaxDelay:         c.MaxDelay,
+		LockoutThreshold: c.LockoutThreshold,
+		LockoutDuration:  c.LockoutDuration,
+		Window:           c.Window,
+	})
 }
 
 func run(ctx context.Context, w io.Writer, args []string) (err error) {
@@ -102,20 +225,77 @@ func run(ctx context.Context, w io.Writer, args []string) (err error) {
 
 	repositories := repository.NewRepositories(db)
 
+	notifier, err := newNotifier(&cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	policy, err := newPolicy(&cfg)
+	if err != nil {
+		return err
+	}
+
+	speller, err := newSpeller(&cfg)
+	if err != nil {
+		return err
+	}
+
+	spellPolicy, err := service.ParseSpellPolicy(cfg.Speller.Policy)
+	if err != nil {
+		return fmt.Errorf("speller.policy: %w", err)
+	}
+
+	signingKeys, err := newSigningKeys(&cfg)
+	if err != nil {
+		return err
+	}
+
+	signInLimits := service.SignInLimits{
+		Username: cfg.SignIn.Username.newLimiter(),
+		IP:       cfg.SignIn.IP.newLimiter(),
+	}
+
 	deps := service.ServicesDependencies{
-		Repositories: repositories,
-		Speller:      speller.NewYandexSpeller(),
-		Secret:       cfg.JWT.Secret,
-		TokenTTL:     cfg.JWT.TokenTTL,
+		Repositories:     repositories,
+		Speller:          speller,
+		SpellPolicy:      spellPolicy,
+		Notifier:         notifier,
+		Policy:           policy,
+		SignInLimits:     signInLimits,
+		SigningKeys:      signingKeys,
+		TokenTTL:         cfg.JWT.TokenTTL,
+		RefreshTokenTTL:  cfg.JWT.RefreshTokenTTL,
+		PasswordResetTTL: cfg.PasswordReset.TokenTTL,
+		PasswordResetLimits: service.SignInLimits{
+			Username: cfg.PasswordReset.Username.newLimiter(),
+			IP:       cfg.PasswordReset.IP.newLimiter(),
+		},
+
+		TwoFactorIssuer:       cfg.TwoFactor.Issuer,
+		TwoFactorChallengeTTL: cfg.TwoFactor.ChallengeTTL,
 	}
 
 	services := service.NewServices(deps)
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if cfg.Trash.PurgeInterval > 0 {
+		if cfg.Trash.Retention <= 0 {
+			return errors.New("trash.retention must be positive when trash.purge_interval is set")
+		}
+		go service.NewTrashPurger(repositories.Note, cfg.Trash.Retention, cfg.Trash.PurgeInterval, logger).Run(ctx)
+	}
+
 	address := net.JoinHostPort(cfg.Server.Host, cfg.Server.Port)
 
 	err = httpserver.New(
 		address,
-		httphandler.New(services, httphandler.WithLogger(logger)),
+		httphandler.New(services,
+			httphandler.WithLogger(logger),
+			httphandler.WithRequireIfMatch(cfg.Notes.RequireIfMatch),
+			httphandler.WithClientIPHeader(cfg.Server.HTTP.ClientIPHeader),
+		),
 		httpserver.WithLogger(logger),
 		httpserver.WithShutdownTimeout(cfg.Server.HTTP.ShutdownTimeout),
 		httpserver.WithReadTimeout(cfg.Server.HTTP.ReadTimeout),
@@ -144,6 +324,162 @@ func connectDB(ctx context.Context, cfg config) (*sql.DB, error) {
 	return db, nil
 }
 
+func newSigningKeys(cfg *config) (*signing.KeySet, error) {
+	var keys []*signing.Key
+
+	if cfg.JWT.Secret != "" {
+		keys = append(keys, signing.NewHMACKey("", cfg.JWT.Secret))
+	}
+
+	for i, k := range cfg.JWT.Keys {
+		if k.ID == "" {
+			return nil, fmt.Errorf("jwt.keys[%d].id must be set", i)
+		}
+
+		var (
+			key *signing.Key
+			err error
+		)
+		switch {
+		case k.PrivateKeyFile != "":
+			key, err = signing.LoadKey(k.ID, k.Algorithm, k.PrivateKeyFile, true)
+		case k.PublicKeyFile != "":
+			key, err = signing.LoadKey(k.ID, k.Algorithm, k.PublicKeyFile, false)
+		default:
+			err = errors.New("private_key_file or public_key_file must be set")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwt.keys[%d]: %w", i, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	keySet, err := signing.NewKeySet(cfg.JWT.SigningKey, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: %w", err)
+	}
+
+	return keySet, nil
+}
+
+func newPolicy(cfg *config) (*service.Policy, error) {
+	username := cfg.Policy.Username
+	password := cfg.Policy.Password
+
+	policy := &service.Policy{
+		Username: service.UsernamePolicy{
+			MinLength: username.MinLength,
+			MaxLength: username.MaxLength,
+			Reserved:  username.Reserved,
+		},
+		Password: service.PasswordPolicy{
+			MinLength:     password.MinLength,
+			MaxLength:     password.MaxLength,
+			RequireUpper:  password.RequireUpper,
+			RequireLower:  password.RequireLower,
+			RequireDigit:  password.RequireDigit,
+			RequireSymbol: password.RequireSymbol,
+		},
+	}
+
+	if username.Pattern != "" {
+		pattern, err := regexp.Compile(username.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("policy.username.pattern: %w", err)
+		}
+		policy.Username.Pattern = pattern
+	}
+
+	if password.DenylistFile != "" {
+		denylist, err := service.LoadDenylist(password.DenylistFile)
+		if err != nil {
+			return nil, fmt.Errorf("policy.password.denylist_file: %w", err)
+		}
+		policy.Password.Denylist = denylist
+	}
+
+	return policy, nil
+}
+
+func newNotifier(cfg *config, logger *slog.Logger) (notifier.Notifier, error) {
+	switch cfg.PasswordReset.Notifier {
+	case "log":
+		return notifier.NewLogNotifier(logger), nil
+	case "file":
+		if cfg.PasswordReset.File == "" {
+			return nil, errors.New("password_reset.file must be set for the file notifier")
+		}
+		return notifier.NewFileNotifier(cfg.PasswordReset.File), nil
+	default:
+		return nil, fmt.Errorf("password_reset.notifier value must be one of [log, file]: '%v'", cfg.PasswordReset.Notifier)
+	}
+}
+
+// newSpeller builds the configured backend, wrapped by the cache and, for
+// the Yandex backend, by the fail-open policy.
+func newSpeller(cfg *config) (speller.Speller, error) {
+	var (
+		s       speller.Speller
+		options string
+	)
+
+	switch cfg.Speller.Backend {
+	case "yandex":
+		yandex := cfg.Speller.Yandex
+		for name, d := range map[string]time.Duration{
+			"connect_timeout":  yandex.ConnectTimeout,
+			"timeout":          yandex.Timeout,
+			"retry_base_delay": yandex.RetryBaseDelay,
+			"retry_max_delay":  yandex.RetryMaxDelay,
+			"breaker_cooldown": yandex.BreakerCooldown,
+		} {
+			if d < 0 {
+				return nil, fmt.Errorf("speller.yandex.%s must not be negative: '%v'", name, d)
+			}
+		}
+		if yandex.Retries < 0 || yandex.BreakerThreshold < 0 {
+			return nil, errors.New("speller.yandex.retries and breaker_threshold must not be negative")
+		}
+
+		optFns := []yandexspeller.OptionFn{
+			yandexspeller.WithTimeouts(yandex.ConnectTimeout, yandex.Timeout),
+			yandexspeller.WithRetries(yandex.Retries, yandex.RetryBaseDelay, yandex.RetryMaxDelay),
+			yandexspeller.WithCircuitBreaker(yandex.BreakerThreshold, yandex.BreakerCooldown),
+		}
+		if yandex.URL != "" {
+			optFns = append(optFns, yandexspeller.WithBaseURL(yandex.URL))
+		}
+		s, options = yandexspeller.NewYandexSpeller(optFns...), "yandex "+yandex.URL
+	case "dictionary":
+		if len(cfg.Speller.Dictionary.Files) == 0 {
+			return nil, errors.New("speller.dictionary.files must be set for the dictionary speller")
+		}
+		d, err := dictionary.New(cfg.Speller.Dictionary.Files...)
+		if err != nil {
+			return nil, err
+		}
+		s, options = d, "dictionary "+strings.Join(cfg.Speller.Dictionary.Files, " ")
+	default:
+		return nil, fmt.Errorf("speller.backend value must be one of [yandex, dictionary]: '%v'", cfg.Speller.Backend)
+	}
+
+	if c := cfg.Speller.Cache; c.Size > 0 {
+		if c.TTL < 0 {
+			return nil, fmt.Errorf("speller.cache.ttl must not be negative: '%v'", c.TTL)
+		}
+		caching := speller.NewCachingSpeller(s, speller.NewMemoryCache(c.Size, c.TTL), options)
+		expvar.Publish("speller_cache", expvar.Func(func() any { return caching.Stats() }))
+		s = caching
+	}
+
+	if cfg.Speller.Backend == "yandex" && cfg.Speller.Yandex.FailOpen {
+		s = speller.NewFailOpenSpeller(s)
+	}
+
+	return s, nil
+}
+
 func initLogger(w io.Writer, cfg *config) (*slog.Logger, error) {
 	logOpts := &slog.HandlerOptions{
 		AddSource: cfg.Logger.AddSource,
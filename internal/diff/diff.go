@@ -0,0 +1,275 @@
+// Package diff produces line-based unified diffs.
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type op struct {
+	kind opKind
+	line string
+}
+
+// MaxLines bounds the total number of lines of the texts Unified compares,
+// which bounds the time a diff takes.
+const MaxLines = 20_000
+
+// ErrTooLarge is returned when the texts have more than MaxLines lines.
+var ErrTooLarge = errors.New("texts too large to diff")
+
+// Unified returns the unified diff turning a into b, with the given number
+// of context lines around each change. It returns an empty string when the
+// texts are equal.
+func Unified(fromName, toName, a, b string, context int) (string, error) {
+	if a == b {
+		return "", nil
+	}
+
+	aLines, bLines := splitLines(a), splitLines(b)
+	if len(aLines)+len(bLines) > MaxLines {
+		return "", ErrTooLarge
+	}
+
+	ops := lineDiff(aLines, bLines)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	for _, h := range hunks(ops, context) {
+		sb.WriteString(h)
+	}
+
+	return sb.String(), nil
+}
+
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineDiff computes the shortest edit script between a and b using the
+// linear space variant of the Myers algorithm, which splits the texts at the
+// middle snake of an optimal path and recurses on both halves.
+func lineDiff(a, b []string) []op {
+	d := &differ{
+		a:   a,
+		b:   b,
+		ops: make([]op, 0, max(len(a), len(b))),
+	}
+	d.compare(0, len(a), 0, len(b))
+
+	return d.ops
+}
+
+type differ struct {
+	a, b []string
+	ops  []op
+	// vf and vb hold the furthest reaching paths of the forward and the
+	// backward search, reused across calls.
+	vf, vb []int
+}
+
+// compare appends the edit script between a[a0:a1] and b[b0:b1].
+func (d *differ) compare(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.ops = append(d.ops, op{opEqual, d.a[a0]})
+		a0++
+		b0++
+	}
+
+	suffix := 0
+	for a1 > a0 && b1 > b0 && d.a[a1-1] == d.b[b1-1] {
+		a1--
+		b1--
+		suffix++
+	}
+
+	switch {
+	case a0 == a1:
+		for _, line := range d.b[b0:b1] {
+			d.ops = append(d.ops, op{opInsert, line})
+		}
+	case b0 == b1:
+		for _, line := range d.a[a0:a1] {
+			d.ops = append(d.ops, op{opDelete, line})
+		}
+	default:
+		// Both ranges are non-empty and differ at both ends, so the edit
+		// distance is at least two and both halves are smaller problems.
+		x, y, u, v := d.middleSnake(a0, a1, b0, b1)
+		d.compare(a0, x, b0, y)
+		for _, line := range d.a[x:u] {
+			d.ops = append(d.ops, op{opEqual, line})
+		}
+		d.compare(u, a1, v, b1)
+	}
+
+	for _, line := range d.a[a1 : a1+suffix] {
+		d.ops = append(d.ops, op{opEqual, line})
+	}
+}
+
+// middleSnake searches forward from the start and backward from the end of
+// the ranges at once, and returns the snake, from (x, y) to (u, v), where the
+// searches meet.
+func (d *differ) middleSnake(a0, a1, b0, b1 int) (int, int, int, int) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+
+	offset := maxD + 1
+	size := 2*maxD + 3
+	if cap(d.vf) < size {
+		d.vf, d.vb = make([]int, size), make([]int, size)
+	}
+	vf, vb := d.vf[:size], d.vb[:size]
+	clear(vf)
+	clear(vb)
+
+	for depth := 0; depth <= maxD; depth++ {
+		for k := -depth; k <= depth; k += 2 {
+			var x int
+			if k == -depth || (k != depth && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x++
+				y++
+			}
+			vf[offset+k] = x
+
+			// The backward paths are on diagonal delta-k, counted from
+			// the ends.
+			if c := delta - k; odd && c >= -(depth-1) && c <= depth-1 && x+vb[offset+c] >= n {
+				return a0 + startX, b0 + startY, a0 + x, b0 + y
+			}
+		}
+
+		for c := -depth; c <= depth; c += 2 {
+			var x int
+			if c == -depth || (c != depth && vb[offset+c-1] < vb[offset+c+1]) {
+				x = vb[offset+c+1]
+			} else {
+				x = vb[offset+c-1] + 1
+			}
+			y := x - c
+			startX, startY := x, y
+			for x < n && y < m && d.a[a1-1-x] == d.b[b1-1-y] {
+				x++
+				y++
+			}
+			vb[offset+c] = x
+
+			if k := delta - c; !odd && k >= -depth && k <= depth && x+vf[offset+k] >= n {
+				return a1 - x, b1 - y, a1 - startX, b1 - startY
+			}
+		}
+	}
+
+	// Unreachable: the searches meet within maxD steps.
+	panic("diff: no middle snake")
+}
+
+// hunks groups the edit script into unified diff hunks.
+func hunks(ops []op, context int) []string {
+	var result []string
+
+	for start := 0; start < len(ops); {
+		// Find the next change.
+		first := start
+		for first < len(ops) && ops[first].kind == opEqual {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// Extend the hunk while changes are close enough to share context.
+		last := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != opEqual {
+				last = i
+			} else if i-last > 2*context {
+				break
+			}
+		}
+
+		from := max(first-context, start)
+		to := min(last+context+1, len(ops))
+
+		result = append(result, formatHunk(ops, from, to))
+		start = to
+	}
+
+	return result
+}
+
+func formatHunk(ops []op, from, to int) string {
+	// Line numbers of the hunk start in both texts.
+	aLine, bLine := 1, 1
+	for _, o := range ops[:from] {
+		if o.kind != opInsert {
+			aLine++
+		}
+		if o.kind != opDelete {
+			bLine++
+		}
+	}
+
+	var body strings.Builder
+	aCount, bCount := 0, 0
+	for _, o := range ops[from:to] {
+		prefix := " "
+		switch o.kind {
+		case opEqual:
+			aCount++
+			bCount++
+		case opDelete:
+			prefix = "-"
+			aCount++
+		case opInsert:
+			prefix = "+"
+			bCount++
+		}
+
+		body.WriteString(prefix + o.line)
+		if !strings.HasSuffix(o.line, "\n") {
+			body.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+
+	// An empty range starts at the line before it, as in GNU diff.
+	if aCount == 0 {
+		aLine--
+	}
+	if bCount == 0 {
+		bLine--
+	}
+
+	return fmt.Sprintf("@@ -%s +%s @@\n%s", hunkRange(aLine, aCount), hunkRange(bLine, bCount), body.String())
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,184 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			a:    "a\nb\nc\n",
+			b:    "a\nx\nc\n",
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "a\nb\n",
+			want: "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "to empty",
+			a:    "a\n",
+			b:    "",
+			want: "--- a\n+++ b\n@@ -1 +0,0 @@\n-a\n",
+		},
+		{
+			name: "no newline at end",
+			a:    "a\nb",
+			b:    "a\nb\n",
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name: "no newline at end of both",
+			a:    "a\nb",
+			b:    "a\nc",
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+		},
+		{
+			name: "separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			b:    "x\n2\n3\n4\n5\n6\n7\n8\n9\ny\n",
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n-1\n+x\n 2\n@@ -9,2 +9,2 @@\n 9\n-10\n+y\n",
+		},
+		{
+			name: "merged hunks",
+			a:    "1\n2\n3\n4\n",
+			b:    "x\n2\n3\ny\n",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n-4\n+y\n",
+		},
+		{
+			name:    "insertion in the middle",
+			a:       "1\n2\n3\n4\n5\n6\n",
+			b:       "1\n2\n3\nx\n4\n5\n6\n",
+			context: 2,
+			want:    "--- a\n+++ b\n@@ -2,4 +2,5 @@\n 2\n 3\n+x\n 4\n 5\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			context := tt.context
+			if context == 0 {
+				context = 1
+			}
+
+			got, err := Unified("a", "b", tt.a, tt.b, context)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedTooLarge(t *testing.T) {
+	a := strings.Repeat("a\n", MaxLines/2)
+	b := strings.Repeat("b\n", MaxLines/2+1)
+
+	if _, err := Unified("a", "b", a, b, 3); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Unified() error = %v, want %v", err, ErrTooLarge)
+	}
+}
+
+// The linear space search must keep memory proportional to the input, even
+// for completely different texts at the line limit.
+func TestUnifiedLargeInputMemory(t *testing.T) {
+	var a, b strings.Builder
+	for i := range MaxLines / 2 {
+		fmt.Fprintf(&a, "a%d\n", i)
+		fmt.Fprintf(&b, "b%d\n", i)
+	}
+
+	allocs := testing.Benchmark(func(tb *testing.B) {
+		tb.ReportAllocs()
+		for range tb.N {
+			if _, err := Unified("a", "b", a.String(), b.String(), 3); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	})
+
+	// The output alone is about 3 times the input.
+	if limit := int64(32 << 20); allocs.AllocedBytesPerOp() > limit {
+		t.Errorf("Unified() allocated %d bytes, want at most %d", allocs.AllocedBytesPerOp(), limit)
+	}
+}
+
+// TestLineDiffMinimal compares the edit scripts of random texts with the
+// edit distance computed by dynamic programming.
+func TestLineDiffMinimal(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+
+	for i := range 500 {
+		a := randomLines(r, r.IntN(12))
+		b := randomLines(r, r.IntN(12))
+
+		ops := lineDiff(a, b)
+
+		var gotA, gotB []string
+		edits := 0
+		for _, o := range ops {
+			if o.kind != opInsert {
+				gotA = append(gotA, o.line)
+			}
+			if o.kind != opDelete {
+				gotB = append(gotB, o.line)
+			}
+			if o.kind != opEqual {
+				edits++
+			}
+		}
+
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("case %d: edit script of %q to %q does not reproduce them", i, a, b)
+		}
+		if want := editDistance(a, b); edits != want {
+			t.Fatalf("case %d: edit script of %q to %q has %d edits, want %d", i, a, b, edits, want)
+		}
+	}
+}
+
+func randomLines(r *rand.Rand, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = string(rune('a'+r.IntN(3))) + "\n"
+	}
+	return lines
+}
+
+// editDistance counts the insertions and deletions turning a into b.
+func editDistance(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	return len(a) + len(b) - 2*lcs[0][0]
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// AccessToken is a long-lived personal access token limited to its scopes.
+type AccessToken struct {
+	ID     int64
+	UserID int64
+	Name   string
+	Scopes []string
+	// ExpiresAt is nil for tokens that never expire.
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
@@ -1,7 +1,91 @@
 package entity
 
+import "time"
+
 type Note struct {
 	ID     int64
 	UserID int64
-	Title  string
+	// NotebookID is nil for notes outside of any notebook.
+	NotebookID *int64
+	Title      string
+	Body       string
+	Tags       []string
+	// Version is incremented on every change of the note.
+	Version   int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// DeletedAt is set for notes in the trash.
+	DeletedAt *time.Time
+}
+
+type NoteSortField string
+
+const (
+	NoteSortID      NoteSortField = "id"
+	NoteSortCreated NoteSortField = "created"
+	NoteSortUpdated NoteSortField = "updated"
+	NoteSortTitle   NoteSortField = "title"
+)
+
+// NoteCursor is the position of the last note of a page. Only the field
+// matching the sort order and the ID are meaningful.
+type NoteCursor struct {
+	ID        int64
+	Title     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// NoteFilter selects a page of notes belonging to UserID.
+type NoteFilter struct {
+	UserID int64
+	// Deleted selects notes in the trash instead of live ones.
+	Deleted       bool
+	NotebookID    *int64
+	Title         string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// Tags restricts the result to notes having all of the tags, or any
+	// of them when TagsMatchAny is set.
+	Tags         []string
+	TagsMatchAny bool
+
+	Sort  NoteSortField
+	Desc  bool
+	After *NoteCursor
+	Limit int
+}
+
+type SearchLanguage string
+
+const (
+	SearchLanguageEnglish SearchLanguage = "english"
+	SearchLanguageRussian SearchLanguage = "russian"
+)
+
+// NoteSearch is a full-text query over the notes of UserID.
+type NoteSearch struct {
+	UserID   int64
+	Query    string
+	Language SearchLanguage
+	Limit    int
+	Offset   int
+}
+
+// NoteSearchResult is a matched note with its rank and highlighted fragments.
+type NoteSearchResult struct {
+	Note
+	Rank           float64
+	TitleHighlight string
+	BodySnippet    string
+}
+
+// NoteRevision is an immutable snapshot of a note taken on every change.
+type NoteRevision struct {
+	NoteID    int64
+	Revision  int
+	Title     string
+	Body      string
+	Tags      []string
+	CreatedAt time.Time
 }
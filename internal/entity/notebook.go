@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+type Notebook struct {
+	ID     int64
+	UserID int64
+	// ParentID is nil for top-level notebooks.
+	ParentID  *int64
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// Session groups the access and refresh tokens issued by one sign-in.
+type Session struct {
+	ID        string
+	UserID    int64
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+// RefreshToken is a single-use token of a session. Only its hash is stored.
+type RefreshToken struct {
+	Hash      []byte
+	SessionID string
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
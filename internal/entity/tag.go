@@ -0,0 +1,8 @@
+package entity
+
+type Tag struct {
+	ID        int64
+	UserID    int64
+	Name      string
+	NoteCount int
+}
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// TwoFactor is the TOTP enrollment of a user. It is enforced at sign-in only
+// once confirmed.
+type TwoFactor struct {
+	UserID int64
+	Secret string
+	// LastStep is the time step of the last accepted code.
+	LastStep    int64
+	CreatedAt   time.Time
+	ConfirmedAt *time.Time
+}
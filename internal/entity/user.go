@@ -2,9 +2,26 @@ package entity
 
 import "time"
 
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
 type User struct {
 	ID        int64
 	Username  string
 	Password  []byte
+	Role      Role
 	CreatedAt time.Time
+	// DisabledAt is set for accounts disabled by an admin.
+	DisabledAt *time.Time
+}
+
+// UserStats is a user together with the number of their notes, trashed ones
+// excluded.
+type UserStats struct {
+	User
+	NoteCount int64
 }
@@ -5,12 +5,18 @@ import (
 	"net/http"
 )
 
+// MaxBodySize bounds the size of decoded request bodies.
+const MaxBodySize = 1 << 20
+
 func Encode(code int, w http.ResponseWriter, v any) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 	return json.NewEncoder(w).Encode(v)
 }
 
+// Decode reads at most MaxBodySize bytes of the body. A larger body fails
+// with *http.MaxBytesError.
 func Decode(r *http.Request, v any) error {
+	r.Body = http.MaxBytesReader(nil, r.Body, MaxBodySize)
 	return json.NewDecoder(r.Body).Decode(v)
 }
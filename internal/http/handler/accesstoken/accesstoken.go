@@ -0,0 +1,119 @@
+package accesstoken
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/http/encoding"
+	contexthelper "github.com/bojackodin/notes/internal/http/handler/context"
+	"github.com/bojackodin/notes/internal/http/httperror"
+	"github.com/bojackodin/notes/internal/log"
+	"github.com/bojackodin/notes/internal/service"
+)
+
+type Controller struct {
+	tokens service.AccessToken
+}
+
+func New(tokens service.AccessToken) *Controller {
+	return &Controller{
+		tokens: tokens,
+	}
+}
+
+type accessTokenResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// Token is only returned on creation.
+	Token string `json:"token,omitempty"`
+}
+
+func newAccessTokenResponse(token entity.AccessToken) *accessTokenResponse {
+	return &accessTokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Scopes:     token.Scopes,
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+	}
+}
+
+type createAccessTokenInput struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (ctrl *Controller) CreateAccessToken(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	var input createAccessTokenInput
+	if err := encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	token, secret, err := ctrl.tokens.CreateAccessToken(r.Context(), userID, service.CreateAccessTokenInput{
+		Name:      input.Name,
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+	})
+	if err != nil {
+		logger.Error("failed to create access token", log.Err(err))
+		return err
+	}
+
+	response := newAccessTokenResponse(token)
+	response.Token = secret
+
+	_ = encoding.Encode(http.StatusCreated, w, response)
+	return nil
+}
+
+type listAccessTokensResponse []*accessTokenResponse
+
+func (ctrl *Controller) ListAccessTokens(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	tokens, err := ctrl.tokens.ListAccessTokens(r.Context(), userID)
+	if err != nil {
+		logger.Error("failed to list access tokens", log.Err(err))
+		return err
+	}
+
+	response := make(listAccessTokensResponse, 0, len(tokens))
+	for _, token := range tokens {
+		response = append(response, newAccessTokenResponse(token))
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, &response)
+	return nil
+}
+
+func (ctrl *Controller) RevokeAccessToken(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return httperror.WithStatusError(errors.New("invalid access token id"), http.StatusBadRequest)
+	}
+
+	if err = ctrl.tokens.RevokeAccessToken(r.Context(), id, userID); err != nil {
+		logger.Error("failed to revoke access token", log.Err(err))
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -0,0 +1,207 @@
+package admin
+
+import (
+	"errors"
+	"expvar"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/http/encoding"
+	contexthelper "github.com/bojackodin/notes/internal/http/handler/context"
+	"github.com/bojackodin/notes/internal/http/httperror"
+	"github.com/bojackodin/notes/internal/log"
+	"github.com/bojackodin/notes/internal/service"
+)
+
+type Controller struct {
+	admin service.Admin
+}
+
+func New(admin service.Admin) *Controller {
+	return &Controller{
+		admin: admin,
+	}
+}
+
+type userResponse struct {
+	ID         int64       `json:"id"`
+	Username   string      `json:"username"`
+	Role       entity.Role `json:"role"`
+	CreatedAt  time.Time   `json:"created_at"`
+	DisabledAt *time.Time  `json:"disabled_at"`
+	NoteCount  int64       `json:"note_count"`
+}
+
+func newUserResponse(user entity.UserStats) *userResponse {
+	return &userResponse{
+		ID:         user.ID,
+		Username:   user.Username,
+		Role:       user.Role,
+		CreatedAt:  user.CreatedAt,
+		DisabledAt: user.DisabledAt,
+		NoteCount:  user.NoteCount,
+	}
+}
+
+type listUsersResponse []*userResponse
+
+func (ctrl *Controller) ListUsers(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	query := r.URL.Query()
+
+	var limit, offset int
+	for name, dst := range map[string]*int{"limit": &limit, "offset": &offset} {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return httperror.WithStatusError(errors.New("invalid "+name), http.StatusBadRequest)
+		}
+		*dst = n
+	}
+
+	users, err := ctrl.admin.ListUsers(r.Context(), limit, offset)
+	if err != nil {
+		logger.Error("failed to list users", log.Err(err))
+		return err
+	}
+
+	response := make(listUsersResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, newUserResponse(user))
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, &response)
+	return nil
+}
+
+func (ctrl *Controller) GetUser(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+
+	id, err := userID(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := ctrl.admin.GetUser(r.Context(), id)
+	if err != nil {
+		logger.Error("failed to get user", log.Err(err))
+		return err
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, newUserResponse(user))
+	return nil
+}
+
+func (ctrl *Controller) DisableUser(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	actorID := contexthelper.ContextGetUserID(r)
+
+	id, err := userID(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := ctrl.admin.DisableUser(r.Context(), actorID, id)
+	if err != nil {
+		logger.Error("failed to disable user", log.Err(err))
+		return err
+	}
+
+	logger.Info("user disabled", "admin_id", actorID, "user_id", id)
+
+	_ = encoding.Encode(http.StatusOK, w, newUserResponse(user))
+	return nil
+}
+
+func (ctrl *Controller) EnableUser(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	actorID := contexthelper.ContextGetUserID(r)
+
+	id, err := userID(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := ctrl.admin.EnableUser(r.Context(), id)
+	if err != nil {
+		logger.Error("failed to enable user", log.Err(err))
+		return err
+	}
+
+	logger.Info("user enabled", "admin_id", actorID, "user_id", id)
+
+	_ = encoding.Encode(http.StatusOK, w, newUserResponse(user))
+	return nil
+}
+
+func (ctrl *Controller) SignOutUser(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	actorID := contexthelper.ContextGetUserID(r)
+
+	id, err := userID(r)
+	if err != nil {
+		return err
+	}
+
+	if err = ctrl.admin.SignOutUser(r.Context(), actorID, id); err != nil {
+		logger.Error("failed to sign out user", log.Err(err))
+		return err
+	}
+
+	logger.Info("user signed out", "admin_id", actorID, "user_id", id)
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type setRoleInput struct {
+	Role entity.Role `json:"role"`
+}
+
+func (ctrl *Controller) SetRole(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	actorID := contexthelper.ContextGetUserID(r)
+
+	id, err := userID(r)
+	if err != nil {
+		return err
+	}
+
+	var input setRoleInput
+	if err = encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	user, err := ctrl.admin.SetRole(r.Context(), actorID, id, input.Role)
+	if err != nil {
+		logger.Error("failed to set role", log.Err(err))
+		return err
+	}
+
+	logger.Info("user role changed", "admin_id", actorID, "user_id", id, "role", input.Role)
+
+	_ = encoding.Encode(http.StatusOK, w, newUserResponse(user))
+	return nil
+}
+
+// Metrics serves the published expvar variables, such as the speller cache
+// hits and misses.
+func (ctrl *Controller) Metrics(w http.ResponseWriter, r *http.Request) error {
+	expvar.Handler().ServeHTTP(w, r)
+	return nil
+}
+
+func userID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, httperror.WithStatusError(errors.New("invalid user id"), http.StatusBadRequest)
+	}
+
+	return id, nil
+}
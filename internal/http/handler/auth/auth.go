@@ -1,22 +1,43 @@
 package auth
 
 import (
-	"errors"
 	"net/http"
 
 	"github.com/bojackodin/notes/internal/http/encoding"
+	contexthelper "github.com/bojackodin/notes/internal/http/handler/context"
 	"github.com/bojackodin/notes/internal/http/httperror"
 	"github.com/bojackodin/notes/internal/log"
 	"github.com/bojackodin/notes/internal/service"
 )
 
 type Controller struct {
-	auth service.Auth
+	auth           service.Auth
+	clientIPHeader string
 }
 
-func New(auth service.Auth) *Controller {
+func New(auth service.Auth, optFns ...OptionFn) *Controller {
+	options := &options{}
+	for _, fn := range optFns {
+		fn(options)
+	}
+
 	return &Controller{
-		auth: auth,
+		auth:           auth,
+		clientIPHeader: options.clientIPHeader,
+	}
+}
+
+type options struct {
+	clientIPHeader string
+}
+
+type OptionFn func(*options)
+
+// WithClientIPHeader takes the client IP from the given header, such as
+// X-Real-IP, set by a trusted reverse proxy.
+func WithClientIPHeader(header string) OptionFn {
+	return func(o *options) {
+		o.clientIPHeader = header
 	}
 }
 
@@ -41,11 +62,7 @@ func (ctrl *Controller) SignUp(w http.ResponseWriter, r *http.Request) error {
 	id, err := ctrl.auth.CreateUser(r.Context(), input.Username, input.Password)
 	if err != nil {
 		logger.Error("failed to create user", log.Err(err))
-		code := http.StatusInternalServerError
-		if errors.Is(err, service.ErrUserDuplicate) {
-			code = http.StatusBadRequest
-		}
-		return httperror.WithStatusError(err, code)
+		return err
 	}
 
 	_ = encoding.Encode(http.StatusCreated, w, &signUpResponse{ID: id})
@@ -58,7 +75,8 @@ type signInInput struct {
 }
 
 type signInResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 func (ctrl *Controller) SignIn(w http.ResponseWriter, r *http.Request) error {
@@ -70,12 +88,104 @@ func (ctrl *Controller) SignIn(w http.ResponseWriter, r *http.Request) error {
 		return httperror.WithStatusError(err, http.StatusBadRequest)
 	}
 
-	token, err := ctrl.auth.GenerateToken(r.Context(), input.Username, input.Password)
+	tokens, err := ctrl.auth.GenerateToken(r.Context(), input.Username, input.Password, ctrl.clientIP(r))
 	if err != nil {
 		logger.Error("failed to generate token", log.Err(err))
 		return err
 	}
 
-	_ = encoding.Encode(http.StatusOK, w, &signInResponse{Token: token})
+	if tokens.ChallengeToken != "" {
+		_ = encoding.Encode(http.StatusOK, w, &challengeResponse{ChallengeToken: tokens.ChallengeToken})
+		return nil
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, &signInResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+	return nil
+}
+
+// challengeResponse is returned by SignIn instead of the tokens if the user
+// has two-factor authentication enabled.
+type challengeResponse struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+type verifyTwoFactorInput struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+func (ctrl *Controller) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+
+	var input verifyTwoFactorInput
+	if err := encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	tokens, err := ctrl.auth.VerifyTwoFactor(r.Context(), input.ChallengeToken, input.Code, input.RecoveryCode)
+	if err != nil {
+		logger.Error("failed to verify two-factor code", log.Err(err))
+		return err
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, &signInResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
 	return nil
 }
+
+type refreshInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (ctrl *Controller) Refresh(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+
+	var input refreshInput
+	if err := encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	tokens, err := ctrl.auth.Refresh(r.Context(), input.RefreshToken)
+	if err != nil {
+		logger.Error("failed to refresh token", log.Err(err))
+		return err
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, &signInResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+	return nil
+}
+
+func (ctrl *Controller) SignOut(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	sessionID := contexthelper.ContextGetSessionID(r)
+
+	if err := ctrl.auth.SignOut(r.Context(), sessionID); err != nil {
+		logger.Error("failed to sign out", log.Err(err))
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// JWKS serves the public keys access tokens can be verified with.
+func (ctrl *Controller) JWKS(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	_ = encoding.Encode(http.StatusOK, w, ctrl.auth.JWKS())
+	return nil
+}
+
+func (ctrl *Controller) clientIP(r *http.Request) string {
+	return contexthelper.ClientIP(r, ctrl.clientIPHeader)
+}
@@ -2,12 +2,20 @@ package context
 
 import (
 	"context"
+	"net"
 	"net/http"
+	"strings"
+
+	"github.com/bojackodin/notes/internal/entity"
 )
 
 type contextKey string
 
-const userContextKey = contextKey("userID")
+const (
+	userContextKey    = contextKey("userID")
+	sessionContextKey = contextKey("sessionID")
+	roleContextKey    = contextKey("role")
+)
 
 func ContextSetUserID(r *http.Request, userID int64) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, userID)
@@ -22,3 +30,56 @@ func ContextGetUserID(r *http.Request) int64 {
 
 	return userID
 }
+
+func ContextSetSessionID(r *http.Request, sessionID string) *http.Request {
+	ctx := context.WithValue(r.Context(), sessionContextKey, sessionID)
+	return r.WithContext(ctx)
+}
+
+func ContextGetSessionID(r *http.Request) string {
+	sessionID, ok := r.Context().Value(sessionContextKey).(string)
+	if !ok {
+		panic("missing session value in request context")
+	}
+
+	return sessionID
+}
+
+func ContextSetRole(r *http.Request, role entity.Role) *http.Request {
+	ctx := context.WithValue(r.Context(), roleContextKey, role)
+	return r.WithContext(ctx)
+}
+
+func ContextGetRole(r *http.Request) entity.Role {
+	role, ok := r.Context().Value(roleContextKey).(entity.Role)
+	if !ok {
+		panic("missing role value in request context")
+	}
+
+	return role
+}
+
+// ClientIP returns the client IP from the header, if set and present, such as
+// X-Real-IP set by a trusted reverse proxy, or the remote address. Of a list,
+// such as X-Forwarded-For, the rightmost value is taken: it is the one
+// appended by the proxy, while the others come from the client and may be
+// forged.
+func ClientIP(r *http.Request, header string) string {
+	if header != "" {
+		if values := r.Header.Values(header); len(values) > 0 {
+			v := values[len(values)-1]
+			if i := strings.LastIndexByte(v, ','); i >= 0 {
+				v = v[i+1:]
+			}
+			if ip := strings.TrimSpace(v); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
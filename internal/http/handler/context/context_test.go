@@ -0,0 +1,36 @@
+package context
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		values []string
+		want   string
+	}{
+		{name: "no header configured", values: []string{"10.0.0.1"}, want: "192.0.2.1"},
+		{name: "header missing", header: "X-Real-IP", want: "192.0.2.1"},
+		{name: "single value", header: "X-Real-IP", values: []string{" 10.0.0.1 "}, want: "10.0.0.1"},
+		{name: "list", header: "X-Forwarded-For", values: []string{"1.1.1.1, 10.0.0.1"}, want: "10.0.0.1"},
+		{name: "several lines", header: "X-Forwarded-For", values: []string{"1.1.1.1", "2.2.2.2, 10.0.0.1"}, want: "10.0.0.1"},
+		{name: "empty last value", header: "X-Forwarded-For", values: []string{"1.1.1.1,"}, want: "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, v := range tt.values {
+				r.Header.Add("X-Real-IP", v)
+				r.Header.Add("X-Forwarded-For", v)
+			}
+
+			if got := ClientIP(r, tt.header); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,15 +1,27 @@
 package handler
 
 import (
+	"errors"
 	"log/slog"
+	"math"
 	"net/http"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/bojackodin/notes/internal/entity"
+	accesstokencontroller "github.com/bojackodin/notes/internal/http/handler/accesstoken"
+	admincontroller "github.com/bojackodin/notes/internal/http/handler/admin"
 	authcontroller "github.com/bojackodin/notes/internal/http/handler/auth"
 	contexthelper "github.com/bojackodin/notes/internal/http/handler/context"
 	notecontroller "github.com/bojackodin/notes/internal/http/handler/note"
+	notebookcontroller "github.com/bojackodin/notes/internal/http/handler/notebook"
+	passwordcontroller "github.com/bojackodin/notes/internal/http/handler/password"
+	tagcontroller "github.com/bojackodin/notes/internal/http/handler/tag"
+	twofactorcontroller "github.com/bojackodin/notes/internal/http/handler/twofactor"
+	usercontroller "github.com/bojackodin/notes/internal/http/handler/user"
 	"github.com/bojackodin/notes/internal/http/httperror"
 	"github.com/bojackodin/notes/internal/log"
 	"github.com/bojackodin/notes/internal/service"
@@ -27,20 +39,106 @@ func New(services *service.Services, optFns ...OptionFn) http.Handler {
 
 	mux := http.NewServeMux()
 
+	authMiddleware := &authMiddleware{services.Auth, services.AccessToken}
+
+	// Routes declaring scopes also accept personal access tokens.
+	notesRead, notesWrite := service.ScopeNotesRead, service.ScopeNotesWrite
+
 	{
-		authctrl := authcontroller.New(services.Auth)
+		authctrl := authcontroller.New(services.Auth, authcontroller.WithClientIPHeader(options.clientIPHeader))
 
 		mux.Handle("POST /sign-up", errorHandler(authctrl.SignUp))
 		mux.Handle("POST /sign-in", errorHandler(authctrl.SignIn))
+		mux.Handle("POST /sign-in/2fa", errorHandler(authctrl.VerifyTwoFactor))
+		mux.Handle("POST /token/refresh", errorHandler(authctrl.Refresh))
+		mux.Handle("POST /sign-out", errorHandler(authMiddleware.authenticate(authctrl.SignOut)))
+		mux.Handle("GET /.well-known/jwks.json", errorHandler(authctrl.JWKS))
+	}
+
+	{
+		userctrl := usercontroller.New(services.User)
+
+		mux.Handle("GET /me", errorHandler(authMiddleware.authenticate(userctrl.Me)))
+		mux.Handle("PATCH /me", errorHandler(authMiddleware.authenticate(userctrl.UpdateMe)))
+		mux.Handle("DELETE /me", errorHandler(authMiddleware.authenticate(userctrl.DeleteMe)))
+		mux.Handle("GET /me/export", errorHandler(authMiddleware.authenticate(userctrl.Export)))
+	}
+
+	{
+		adminctrl := admincontroller.New(services.Admin)
+		admin := func(next func(w http.ResponseWriter, r *http.Request) error) func(w http.ResponseWriter, r *http.Request) error {
+			return authMiddleware.authenticate(requireRole(entity.RoleAdmin, next))
+		}
+
+		mux.Handle("GET /admin/users", errorHandler(admin(adminctrl.ListUsers)))
+		mux.Handle("GET /admin/users/{id}", errorHandler(admin(adminctrl.GetUser)))
+		mux.Handle("POST /admin/users/{id}/disable", errorHandler(admin(adminctrl.DisableUser)))
+		mux.Handle("POST /admin/users/{id}/enable", errorHandler(admin(adminctrl.EnableUser)))
+		mux.Handle("POST /admin/users/{id}/sign-out", errorHandler(admin(adminctrl.SignOutUser)))
+		mux.Handle("PUT /admin/users/{id}/role", errorHandler(admin(adminctrl.SetRole)))
+		mux.Handle("GET /admin/metrics", errorHandler(admin(adminctrl.Metrics)))
+	}
+
+	{
+		passwordctrl := passwordcontroller.New(services.Password, passwordcontroller.WithClientIPHeader(options.clientIPHeader))
+
+		mux.Handle("POST /me/password", errorHandler(authMiddleware.authenticate(passwordctrl.ChangePassword)))
+		mux.Handle("POST /password-reset", errorHandler(passwordctrl.RequestReset))
+		mux.Handle("POST /password-reset/confirm", errorHandler(passwordctrl.ConfirmReset))
+	}
+
+	{
+		twofactorctrl := twofactorcontroller.New(services.TwoFactor)
+
+		mux.Handle("POST /me/2fa", errorHandler(authMiddleware.authenticate(twofactorctrl.Enroll)))
+		mux.Handle("POST /me/2fa/confirm", errorHandler(authMiddleware.authenticate(twofactorctrl.Confirm)))
+		mux.Handle("DELETE /me/2fa", errorHandler(authMiddleware.authenticate(twofactorctrl.Disable)))
+	}
+
+	{
+		accesstokenctrl := accesstokencontroller.New(services.AccessToken)
+
+		mux.Handle("GET /me/tokens", errorHandler(authMiddleware.authenticate(accesstokenctrl.ListAccessTokens)))
+		mux.Handle("POST /me/tokens", errorHandler(authMiddleware.authenticate(accesstokenctrl.CreateAccessToken)))
+		mux.Handle("DELETE /me/tokens/{id}", errorHandler(authMiddleware.authenticate(accesstokenctrl.RevokeAccessToken)))
 	}
 
-	authMiddleware := &authMiddleware{services.Auth}
+	{
+		notectrl := notecontroller.New(services.Note, notecontroller.WithRequireIfMatch(options.requireIfMatch))
+
+		mux.Handle("GET /notes", errorHandler(authMiddleware.authenticate(notectrl.ListNotes, notesRead)))
+		mux.Handle("POST /notes", errorHandler(authMiddleware.authenticate(notectrl.CreateNote, notesWrite)))
+		mux.Handle("GET /notes/search", errorHandler(authMiddleware.authenticate(notectrl.SearchNotes, notesRead)))
+		mux.Handle("GET /notes/{id}", errorHandler(authMiddleware.authenticate(notectrl.GetNote, notesRead)))
+		mux.Handle("PUT /notes/{id}", errorHandler(authMiddleware.authenticate(notectrl.ReplaceNote, notesWrite)))
+		mux.Handle("PATCH /notes/{id}", errorHandler(authMiddleware.authenticate(notectrl.UpdateNote, notesWrite)))
+		mux.Handle("DELETE /notes/{id}", errorHandler(authMiddleware.authenticate(notectrl.DeleteNote, notesWrite)))
+		mux.Handle("POST /notes/{id}/move", errorHandler(authMiddleware.authenticate(notectrl.MoveNote, notesWrite)))
+		mux.Handle("POST /notes/{id}/restore", errorHandler(authMiddleware.authenticate(notectrl.RestoreNote, notesWrite)))
+		mux.Handle("GET /notes/{id}/revisions", errorHandler(authMiddleware.authenticate(notectrl.ListRevisions, notesRead)))
+		mux.Handle("GET /notes/{id}/revisions/diff", errorHandler(authMiddleware.authenticate(notectrl.DiffRevisions, notesRead)))
+		mux.Handle("GET /notes/{id}/revisions/{rev}", errorHandler(authMiddleware.authenticate(notectrl.GetRevision, notesRead)))
+		mux.Handle("POST /notes/{id}/revisions/{rev}/restore", errorHandler(authMiddleware.authenticate(notectrl.RestoreRevision, notesWrite)))
+		mux.Handle("GET /trash", errorHandler(authMiddleware.authenticate(notectrl.ListTrash, notesRead)))
+		mux.Handle("DELETE /trash", errorHandler(authMiddleware.authenticate(notectrl.EmptyTrash, notesWrite)))
+		mux.Handle("GET /notebooks/{id}/notes", errorHandler(authMiddleware.authenticate(notectrl.ListNotebookNotes, notesRead)))
+	}
+
+	{
+		notebookctrl := notebookcontroller.New(services.Notebook)
+
+		mux.Handle("GET /notebooks", errorHandler(authMiddleware.authenticate(notebookctrl.ListNotebooks, notesRead)))
+		mux.Handle("POST /notebooks", errorHandler(authMiddleware.authenticate(notebookctrl.CreateNotebook, notesWrite)))
+		mux.Handle("GET /notebooks/{id}", errorHandler(authMiddleware.authenticate(notebookctrl.GetNotebook, notesRead)))
+		mux.Handle("PATCH /notebooks/{id}", errorHandler(authMiddleware.authenticate(notebookctrl.RenameNotebook, notesWrite)))
+		mux.Handle("POST /notebooks/{id}/move", errorHandler(authMiddleware.authenticate(notebookctrl.MoveNotebook, notesWrite)))
+		mux.Handle("DELETE /notebooks/{id}", errorHandler(authMiddleware.authenticate(notebookctrl.DeleteNotebook, notesWrite)))
+	}
 
 	{
-		notectrl := notecontroller.New(services.Note)
+		tagctrl := tagcontroller.New(services.Tag)
 
-		mux.Handle("GET /notes", errorHandler(authMiddleware.authenticate(notectrl.ListNotes)))
-		mux.Handle("POST /notes", errorHandler(authMiddleware.authenticate(notectrl.CreateNote)))
+		mux.Handle("GET /tags", errorHandler(authMiddleware.authenticate(tagctrl.ListTags, notesRead)))
 	}
 
 	handler := loggingMiddleware(options.logger)(mux)
@@ -50,7 +148,9 @@ func New(services *service.Services, optFns ...OptionFn) http.Handler {
 }
 
 type options struct {
-	logger *slog.Logger
+	logger         *slog.Logger
+	requireIfMatch bool
+	clientIPHeader string
 }
 
 type OptionFn func(*options)
@@ -61,13 +161,89 @@ func WithLogger(logger *slog.Logger) OptionFn {
 	}
 }
 
+// WithRequireIfMatch makes writes to an existing note require an If-Match header.
+func WithRequireIfMatch(require bool) OptionFn {
+	return func(o *options) {
+		o.requireIfMatch = require
+	}
+}
+
+// WithClientIPHeader takes the client IP from the given header set by a
+// trusted reverse proxy instead of the connection address.
+func WithClientIPHeader(header string) OptionFn {
+	return func(o *options) {
+		o.clientIPHeader = header
+	}
+}
+
+// errorHandler responds with the error returned by next. Errors without an
+// explicit status get the one of their service.Kind. The messages of server
+// errors are not exposed.
 func errorHandler(next func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if err := next(w, r); err != nil {
-			if code := httperror.HTTPStatus(err); code != 0 {
-				httperror.RespondWithError(w, err.Error(), code)
-			}
+		err := next(w, r)
+		if err == nil {
+			return
 		}
+
+		code, ok := httperror.HTTPStatus(err)
+		if !ok {
+			code = kindStatus(service.KindOf(err))
+		}
+
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			code = http.StatusRequestEntityTooLarge
+		}
+
+		var retryErr *service.TooManyAttemptsError
+		if errors.As(err, &retryErr) {
+			seconds := int(math.Ceil(retryErr.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		}
+
+		msg := err.Error()
+		if code >= http.StatusInternalServerError {
+			msg = http.StatusText(code)
+		}
+
+		if details, ok := httperror.Details(err); ok {
+			httperror.RespondWithDetails(w, msg, details, code)
+			return
+		}
+
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			httperror.RespondWithDetails(w, msg, validationErr.Fields, code)
+			return
+		}
+
+		httperror.RespondWithError(w, msg, code)
+	}
+}
+
+func kindStatus(kind service.Kind) int {
+	switch kind {
+	case service.KindInvalid:
+		return http.StatusBadRequest
+	case service.KindUnauthenticated:
+		return http.StatusUnauthorized
+	case service.KindForbidden:
+		return http.StatusForbidden
+	case service.KindNotFound:
+		return http.StatusNotFound
+	case service.KindConflict:
+		return http.StatusConflict
+	case service.KindPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case service.KindUnprocessable:
+		return http.StatusUnprocessableEntity
+	case service.KindTooManyRequests:
+		return http.StatusTooManyRequests
+	case service.KindUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
 	}
 }
 
@@ -109,10 +285,13 @@ func loggingMiddleware(logger *slog.Logger) func(next http.Handler) http.Handler
 }
 
 type authMiddleware struct {
-	auth service.Auth
+	auth   service.Auth
+	tokens service.AccessToken
 }
 
-func (h *authMiddleware) authenticate(next func(w http.ResponseWriter, r *http.Request) error) func(w http.ResponseWriter, r *http.Request) error {
+// authenticate accepts a JWT or, if the route declares the scopes it
+// requires, a personal access token having all of them.
+func (h *authMiddleware) authenticate(next func(w http.ResponseWriter, r *http.Request) error, scopes ...string) func(w http.ResponseWriter, r *http.Request) error {
 	return func(w http.ResponseWriter, r *http.Request) error {
 		authorizationHeader := r.Header.Get("Authorization")
 
@@ -131,12 +310,56 @@ func (h *authMiddleware) authenticate(next func(w http.ResponseWriter, r *http.R
 			return httperror.WithStatus(http.StatusUnauthorized)
 		}
 
-		userID, err := h.auth.ParseToken(token)
+		if strings.HasPrefix(token, service.AccessTokenPrefix) {
+			return h.authenticateAccessToken(w, r, next, token, scopes)
+		}
+
+		claims, err := h.auth.ParseToken(r.Context(), token)
 		if err != nil {
 			return httperror.WithStatus(http.StatusUnauthorized)
 		}
 
-		r = contexthelper.ContextSetUserID(r, userID)
+		r = contexthelper.ContextSetUserID(r, claims.UserID)
+		r = contexthelper.ContextSetSessionID(r, claims.Id)
+		r = contexthelper.ContextSetRole(r, claims.Role)
+
+		return next(w, r)
+	}
+}
+
+func (h *authMiddleware) authenticateAccessToken(w http.ResponseWriter, r *http.Request, next func(w http.ResponseWriter, r *http.Request) error, token string, scopes []string) error {
+	accessToken, err := h.tokens.ParseAccessToken(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAccessToken) {
+			return httperror.WithStatus(http.StatusUnauthorized)
+		}
+		return err
+	}
+
+	if len(scopes) == 0 {
+		return httperror.WithStatusError(errors.New("personal access tokens are not accepted"), http.StatusForbidden)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(accessToken.Scopes, scope) {
+			return httperror.WithStatusError(errors.New("missing scope "+scope), http.StatusForbidden)
+		}
+	}
+
+	r = contexthelper.ContextSetUserID(r, accessToken.UserID)
+	r = contexthelper.ContextSetSessionID(r, "")
+	// Personal access tokens never carry a role.
+	r = contexthelper.ContextSetRole(r, "")
+
+	return next(w, r)
+}
+
+// requireRole allows authenticated requests whose access token carries the
+// role. It must be wrapped by authenticate.
+func requireRole(role entity.Role, next func(w http.ResponseWriter, r *http.Request) error) func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if contexthelper.ContextGetRole(r) != role {
+			return httperror.WithStatus(http.StatusForbidden)
+		}
 
 		return next(w, r)
 	}
@@ -0,0 +1,113 @@
+package note
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bojackodin/notes/internal/http/httperror"
+	"github.com/bojackodin/notes/internal/service"
+)
+
+// etag returns the strong entity tag of a note version.
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", etag(version))
+}
+
+// ifMatch returns the note version required by the If-Match header, or zero
+// if any version is acceptable. Of a list of entity tags, the one of the
+// current version is required, so that the write still fails if the note
+// changes meanwhile, and ErrVersionMismatch is returned if none matches. A
+// missing header is rejected with 428 when the controller requires
+// conditional writes.
+func (ctrl *Controller) ifMatch(r *http.Request, id, userID int64) (int, error) {
+	header := strings.TrimSpace(strings.Join(r.Header.Values("If-Match"), ","))
+
+	switch header {
+	case "":
+		if ctrl.options.requireIfMatch {
+			return 0, httperror.WithStatusError(errors.New("If-Match header is required"), http.StatusPreconditionRequired)
+		}
+		return 0, nil
+	case "*":
+		return 0, nil
+	}
+
+	var versions []int
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+
+		// Weak tags never match under the strong comparison required by
+		// If-Match, and neither do tags that are not note versions.
+		opaque, weak := strings.CutPrefix(tag, "W/")
+		if !isETag(opaque) {
+			return 0, httperror.WithStatusError(errors.New("If-Match must be a list of entity tags or *"), http.StatusBadRequest)
+		}
+		if version, ok := parseETag(opaque); ok && !weak {
+			versions = append(versions, version)
+		}
+	}
+
+	switch len(versions) {
+	case 0:
+		return 0, service.ErrVersionMismatch
+	case 1:
+		return versions[0], nil
+	}
+
+	note, err := ctrl.notes.GetNote(r.Context(), id, userID)
+	if err != nil {
+		return 0, err
+	}
+	if !slices.Contains(versions, note.Version) {
+		return 0, service.ErrVersionMismatch
+	}
+
+	return note.Version, nil
+}
+
+// notModified reports whether the If-None-Match header matches the version,
+// using the weak comparison.
+func notModified(r *http.Request, version int) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+
+		if v, ok := parseETag(strings.TrimPrefix(tag, "W/")); ok && v == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isETag reports whether the tag is a quoted, strong entity tag.
+func isETag(tag string) bool {
+	return len(tag) >= 2 && tag[0] == '"' && tag[len(tag)-1] == '"' && !strings.Contains(tag[1:len(tag)-1], `"`)
+}
+
+func parseETag(tag string) (int, bool) {
+	if !isETag(tag) {
+		return 0, false
+	}
+
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+
+	return version, true
+}
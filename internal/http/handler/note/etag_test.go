@@ -0,0 +1,88 @@
+package note
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/http/httperror"
+	"github.com/bojackodin/notes/internal/service"
+)
+
+// noteService answers GetNote with a note of the given version; the other
+// methods are not used by the tests.
+type noteService struct {
+	service.Note
+	version int
+	calls   int
+}
+
+func (s *noteService) GetNote(_ context.Context, id, _ int64) (entity.Note, error) {
+	s.calls++
+	return entity.Note{ID: id, Version: s.version}, nil
+}
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      []string
+		require     bool
+		want        int
+		wantStatus  int
+		wantGetNote bool
+	}{
+		{name: "missing", want: 0},
+		{name: "missing but required", require: true, wantStatus: http.StatusPreconditionRequired},
+		{name: "any", header: []string{"*"}, want: 0},
+		{name: "single", header: []string{`"3"`}, want: 3},
+		{name: "single other version", header: []string{`"4"`}, want: 4},
+		{name: "list with the current version", header: []string{`"3", "4"`}, want: 4, wantGetNote: true},
+		{name: "several header lines", header: []string{`"3"`, `"4"`}, want: 4, wantGetNote: true},
+		{name: "list without the current version", header: []string{`"2", "3"`}, wantStatus: http.StatusPreconditionFailed, wantGetNote: true},
+		{name: "weak", header: []string{`W/"4"`}, wantStatus: http.StatusPreconditionFailed},
+		{name: "weak and strong", header: []string{`W/"4", "3"`}, want: 3},
+		{name: "not a version", header: []string{`"abc"`}, wantStatus: http.StatusPreconditionFailed},
+		{name: "unquoted", header: []string{`4`}, wantStatus: http.StatusBadRequest},
+		{name: "any in a list", header: []string{`*, "4"`}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notes := &noteService{version: 4}
+			ctrl := New(notes, WithRequireIfMatch(tt.require))
+
+			r := httptest.NewRequest(http.MethodPut, "/notes/1", nil)
+			for _, v := range tt.header {
+				r.Header.Add("If-Match", v)
+			}
+
+			got, err := ctrl.ifMatch(r, 1, 1)
+			if status := statusOf(err); status != tt.wantStatus {
+				t.Fatalf("ifMatch() error = %v, want status %d", err, tt.wantStatus)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ifMatch() = %d, want %d", got, tt.want)
+			}
+			if called := notes.calls > 0; called != tt.wantGetNote {
+				t.Errorf("GetNote called = %v, want %v", called, tt.wantGetNote)
+			}
+		})
+	}
+}
+
+// statusOf returns the status the error is answered with, or zero for nil.
+func statusOf(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	if status, ok := httperror.HTTPStatus(err); ok {
+		return status
+	}
+	if service.KindOf(err) == service.KindPreconditionFailed {
+		return http.StatusPreconditionFailed
+	}
+
+	return http.StatusInternalServerError
+}
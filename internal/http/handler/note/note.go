@@ -2,32 +2,60 @@ package note
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/bojackodin/notes/internal/entity"
 	"github.com/bojackodin/notes/internal/http/encoding"
 	contexthelper "github.com/bojackodin/notes/internal/http/handler/context"
 	"github.com/bojackodin/notes/internal/http/httperror"
 	"github.com/bojackodin/notes/internal/log"
 	"github.com/bojackodin/notes/internal/service"
-	"github.com/bojackodin/notes/internal/yandex/speller"
 )
 
 type Controller struct {
-	notes service.Note
+	notes   service.Note
+	options *options
 }
 
-func New(notes service.Note) *Controller {
+func New(notes service.Note, optFns ...OptionFn) *Controller {
+	options := &options{}
+	for _, fn := range optFns {
+		fn(options)
+	}
+
 	return &Controller{
-		notes: notes,
+		notes:   notes,
+		options: options,
+	}
+}
+
+type options struct {
+	requireIfMatch bool
+}
+
+type OptionFn func(*options)
+
+// WithRequireIfMatch makes writes to an existing note without an If-Match
+// header fail with 428 Precondition Required.
+func WithRequireIfMatch(require bool) OptionFn {
+	return func(o *options) {
+		o.requireIfMatch = require
 	}
 }
 
 type createNoteInput struct {
-	Title string `json:"title"`
+	Title      string   `json:"title"`
+	Body       string   `json:"body"`
+	Tags       []string `json:"tags"`
+	NotebookID *int64   `json:"notebook_id"`
 }
 
 type createNoteResponse struct {
 	ID int64 `json:"id"`
+	spellingResponse
 }
 
 func (ctrl *Controller) CreateNote(w http.ResponseWriter, r *http.Request) error {
@@ -40,43 +68,395 @@ func (ctrl *Controller) CreateNote(w http.ResponseWriter, r *http.Request) error
 		return httperror.WithStatusError(err, http.StatusBadRequest)
 	}
 
-	id, err := ctrl.notes.CreateNote(r.Context(), input.Title, userID)
+	note, check, err := ctrl.notes.CreateNote(r.Context(), userID, service.CreateNoteInput{
+		Title:       input.Title,
+		Body:        input.Body,
+		Tags:        input.Tags,
+		NotebookID:  input.NotebookID,
+		SpellPolicy: r.URL.Query().Get("spell_policy"),
+	})
 	if err != nil {
-		code := http.StatusInternalServerError
-		var spellErr *speller.SpellError
-		if errors.As(err, &spellErr) {
-			code = http.StatusUnprocessableEntity
-		}
-		logger.Error("failed to create task", log.Err(err))
-		return httperror.WithStatusError(err, code)
+		logger.Error("failed to create note", log.Err(err))
+		return withMisspells(err)
 	}
 
-	_ = encoding.Encode(http.StatusCreated, w, &createNoteResponse{ID: id})
+	setETag(w, note.Version)
+	_ = encoding.Encode(http.StatusCreated, w, &createNoteResponse{
+		ID:               note.ID,
+		spellingResponse: newSpellingResponse(check),
+	})
 	return nil
 }
 
 type noteResponse struct {
-	ID    int64  `json:"id"`
-	Title string `json:"title"`
+	ID         int64      `json:"id"`
+	NotebookID *int64     `json:"notebook_id"`
+	Title      string     `json:"title"`
+	Body       string     `json:"body"`
+	Tags       []string   `json:"tags"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+}
+
+func newNoteResponse(note entity.Note) *noteResponse {
+	return &noteResponse{
+		ID:         note.ID,
+		NotebookID: note.NotebookID,
+		Title:      note.Title,
+		Body:       note.Body,
+		Tags:       note.Tags,
+		CreatedAt:  note.CreatedAt,
+		UpdatedAt:  note.UpdatedAt,
+		DeletedAt:  note.DeletedAt,
+	}
+}
+
+func (ctrl *Controller) GetNote(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	id, err := noteID(r)
+	if err != nil {
+		return err
+	}
+
+	note, err := ctrl.notes.GetNote(r.Context(), id, userID)
+	if err != nil {
+		logger.Error("failed to get note", log.Err(err))
+		return err
+	}
+
+	setETag(w, note.Version)
+	if notModified(r, note.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, newNoteResponse(note))
+	return nil
+}
+
+type updateNoteInput struct {
+	Title *string   `json:"title"`
+	Body  *string   `json:"body"`
+	Tags  *[]string `json:"tags"`
+}
+
+type updateNoteResponse struct {
+	noteResponse
+	spellingResponse
+}
+
+// ReplaceNote handles PUT requests, which must carry every note field.
+func (ctrl *Controller) ReplaceNote(w http.ResponseWriter, r *http.Request) error {
+	return ctrl.updateNote(w, r, true)
+}
+
+// UpdateNote handles PATCH requests, which change only the fields present in the body.
+func (ctrl *Controller) UpdateNote(w http.ResponseWriter, r *http.Request) error {
+	return ctrl.updateNote(w, r, false)
+}
+
+func (ctrl *Controller) updateNote(w http.ResponseWriter, r *http.Request, replace bool) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	id, err := noteID(r)
+	if err != nil {
+		return err
+	}
+
+	version, err := ctrl.ifMatch(r, id, userID)
+	if err != nil {
+		return err
+	}
+
+	var input updateNoteInput
+	if err := encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	if replace && (input.Title == nil || input.Body == nil) {
+		return httperror.WithStatusError(errors.New("title and body are required"), http.StatusBadRequest)
+	}
+	if replace && input.Tags == nil {
+		input.Tags = &[]string{}
+	}
+
+	note, check, err := ctrl.notes.UpdateNote(r.Context(), id, userID, service.UpdateNoteInput{
+		Title:       input.Title,
+		Body:        input.Body,
+		Tags:        input.Tags,
+		Version:     version,
+		SpellPolicy: r.URL.Query().Get("spell_policy"),
+	})
+	if err != nil {
+		logger.Error("failed to update note", log.Err(err))
+		return withMisspells(err)
+	}
+
+	setETag(w, note.Version)
+	_ = encoding.Encode(http.StatusOK, w, &updateNoteResponse{
+		noteResponse:     *newNoteResponse(note),
+		spellingResponse: newSpellingResponse(check),
+	})
+	return nil
+}
+
+type moveNoteInput struct {
+	NotebookID *int64 `json:"notebook_id"`
+}
+
+// MoveNote moves the note into another notebook. A null notebook_id moves
+// the note out of any notebook.
+func (ctrl *Controller) MoveNote(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	id, err := noteID(r)
+	if err != nil {
+		return err
+	}
+
+	version, err := ctrl.ifMatch(r, id, userID)
+	if err != nil {
+		return err
+	}
+
+	var input moveNoteInput
+	if err := encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	note, err := ctrl.notes.MoveNote(r.Context(), id, userID, input.NotebookID, version)
+	if err != nil {
+		logger.Error("failed to move note", log.Err(err))
+		return err
+	}
+
+	setETag(w, note.Version)
+	_ = encoding.Encode(http.StatusOK, w, newNoteResponse(note))
+	return nil
 }
 
-type listNotesResponse []*noteResponse
+func (ctrl *Controller) DeleteNote(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	id, err := noteID(r)
+	if err != nil {
+		return err
+	}
+
+	version, err := ctrl.ifMatch(r, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if err = ctrl.notes.DeleteNote(r.Context(), id, userID, version); err != nil {
+		logger.Error("failed to delete note", log.Err(err))
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (ctrl *Controller) RestoreNote(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	id, err := noteID(r)
+	if err != nil {
+		return err
+	}
+
+	note, err := ctrl.notes.RestoreNote(r.Context(), id, userID)
+	if err != nil {
+		logger.Error("failed to restore note", log.Err(err))
+		return err
+	}
+
+	setETag(w, note.Version)
+	_ = encoding.Encode(http.StatusOK, w, newNoteResponse(note))
+	return nil
+}
+
+type emptyTrashResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+func (ctrl *Controller) EmptyTrash(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	deleted, err := ctrl.notes.EmptyTrash(r.Context(), userID)
+	if err != nil {
+		logger.Error("failed to empty trash", log.Err(err))
+		return err
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, &emptyTrashResponse{Deleted: deleted})
+	return nil
+}
+
+type listNotesResponse struct {
+	Notes      []*noteResponse `json:"notes"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
 
 func (ctrl *Controller) ListNotes(w http.ResponseWriter, r *http.Request) error {
+	params, err := listNotesParams(r)
+	if err != nil {
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	return ctrl.listNotes(w, r, params)
+}
+
+func (ctrl *Controller) ListTrash(w http.ResponseWriter, r *http.Request) error {
+	params, err := listNotesParams(r)
+	if err != nil {
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+	params.Deleted = true
+
+	return ctrl.listNotes(w, r, params)
+}
+
+// ListNotebookNotes lists the notes of the notebook given by the "id" path value.
+func (ctrl *Controller) ListNotebookNotes(w http.ResponseWriter, r *http.Request) error {
+	notebookID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || notebookID <= 0 {
+		return httperror.WithStatusError(errors.New("invalid notebook id"), http.StatusBadRequest)
+	}
+
+	params, err := listNotesParams(r)
+	if err != nil {
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+	params.NotebookID = &notebookID
+
+	return ctrl.listNotes(w, r, params)
+}
+
+func (ctrl *Controller) listNotes(w http.ResponseWriter, r *http.Request, params service.ListNotesParams) error {
 	logger := log.FromContext(r.Context())
 	userID := contexthelper.ContextGetUserID(r)
 
-	notes, err := ctrl.notes.ListNotes(r.Context(), userID)
+	page, err := ctrl.notes.ListNotes(r.Context(), userID, params)
 	if err != nil {
 		logger.Error("failed to list tasks", log.Err(err))
 		return err
 	}
 
-	response := make(listNotesResponse, 0, len(notes))
-	for _, note := range notes {
-		response = append(response, &noteResponse{
-			ID:    note.ID,
-			Title: note.Title,
+	response := listNotesResponse{
+		Notes:      make([]*noteResponse, 0, len(page.Notes)),
+		NextCursor: page.NextCursor,
+	}
+	for _, note := range page.Notes {
+		response.Notes = append(response.Notes, newNoteResponse(note))
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, &response)
+
+	return nil
+}
+
+func listNotesParams(r *http.Request) (service.ListNotesParams, error) {
+	query := r.URL.Query()
+
+	params := service.ListNotesParams{
+		Cursor: query.Get("cursor"),
+		Sort:   query.Get("sort"),
+		Title:  query.Get("title"),
+		Tags:   query["tag"],
+	}
+
+	switch query.Get("tag_mode") {
+	case "", "all":
+	case "any":
+		params.TagsMatchAny = true
+	default:
+		return service.ListNotesParams{}, errors.New("invalid tag_mode: expected all or any")
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return service.ListNotesParams{}, errors.New("invalid limit")
+		}
+		params.Limit = limit
+	}
+
+	for name, dst := range map[string]*time.Time{
+		"created_after":  &params.CreatedAfter,
+		"created_before": &params.CreatedBefore,
+	} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return service.ListNotesParams{}, fmt.Errorf("invalid %s: expected RFC 3339 time", name)
+			}
+			*dst = t.UTC()
+		}
+	}
+
+	return params, nil
+}
+
+type searchResultResponse struct {
+	*noteResponse
+	Rank           float64 `json:"rank"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+}
+
+type searchNotesResponse struct {
+	Results []*searchResultResponse `json:"results"`
+}
+
+func (ctrl *Controller) SearchNotes(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	query := r.URL.Query()
+	params := service.SearchNotesParams{
+		Query: query.Get("q"),
+		Lang:  query.Get("lang"),
+	}
+
+	for name, dst := range map[string]*int{
+		"limit":  &params.Limit,
+		"offset": &params.Offset,
+	} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return httperror.WithStatusError(fmt.Errorf("invalid %s", name), http.StatusBadRequest)
+			}
+			*dst = n
+		}
+	}
+
+	results, err := ctrl.notes.SearchNotes(r.Context(), userID, params)
+	if err != nil {
+		logger.Error("failed to search notes", log.Err(err))
+		return err
+	}
+
+	response := searchNotesResponse{
+		Results: make([]*searchResultResponse, 0, len(results)),
+	}
+	for _, result := range results {
+		response.Results = append(response.Results, &searchResultResponse{
+			noteResponse:   newNoteResponse(result.Note),
+			Rank:           result.Rank,
+			TitleHighlight: result.TitleHighlight,
+			Snippet:        result.BodySnippet,
 		})
 	}
 
@@ -84,3 +464,11 @@ func (ctrl *Controller) ListNotes(w http.ResponseWriter, r *http.Request) error
 
 	return nil
 }
+
+func noteID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, httperror.WithStatusError(errors.New("invalid note id"), http.StatusBadRequest)
+	}
+	return id, nil
+}
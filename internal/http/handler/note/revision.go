@@ -0,0 +1,169 @@
+package note
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/http/encoding"
+	contexthelper "github.com/bojackodin/notes/internal/http/handler/context"
+	"github.com/bojackodin/notes/internal/http/httperror"
+	"github.com/bojackodin/notes/internal/log"
+)
+
+type revisionSummaryResponse struct {
+	Revision  int       `json:"revision"`
+	Title     string    `json:"title"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type revisionResponse struct {
+	Revision  int       `json:"revision"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type listRevisionsResponse []*revisionSummaryResponse
+
+func (ctrl *Controller) ListRevisions(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	id, err := noteID(r)
+	if err != nil {
+		return err
+	}
+
+	revisions, err := ctrl.notes.ListRevisions(r.Context(), id, userID)
+	if err != nil {
+		logger.Error("failed to list revisions", log.Err(err))
+		return err
+	}
+
+	response := make(listRevisionsResponse, 0, len(revisions))
+	for _, rev := range revisions {
+		response = append(response, &revisionSummaryResponse{
+			Revision:  rev.Revision,
+			Title:     rev.Title,
+			Tags:      rev.Tags,
+			CreatedAt: rev.CreatedAt,
+		})
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, &response)
+	return nil
+}
+
+func (ctrl *Controller) GetRevision(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	id, err := noteID(r)
+	if err != nil {
+		return err
+	}
+
+	revision, err := revisionNumber(r.PathValue("rev"), "revision")
+	if err != nil {
+		return err
+	}
+
+	rev, err := ctrl.notes.GetRevision(r.Context(), id, userID, revision)
+	if err != nil {
+		logger.Error("failed to get revision", log.Err(err))
+		return err
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, newRevisionResponse(rev))
+	return nil
+}
+
+type diffResponse struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Diff string `json:"diff"`
+}
+
+// DiffRevisions returns the unified diff between the revisions given by
+// the "from" and "to" query parameters.
+func (ctrl *Controller) DiffRevisions(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	id, err := noteID(r)
+	if err != nil {
+		return err
+	}
+
+	query := r.URL.Query()
+	from, err := revisionNumber(query.Get("from"), "from")
+	if err != nil {
+		return err
+	}
+	to, err := revisionNumber(query.Get("to"), "to")
+	if err != nil {
+		return err
+	}
+
+	diff, err := ctrl.notes.DiffRevisions(r.Context(), id, userID, from, to)
+	if err != nil {
+		logger.Error("failed to diff revisions", log.Err(err))
+		return err
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, &diffResponse{From: from, To: to, Diff: diff})
+	return nil
+}
+
+func (ctrl *Controller) RestoreRevision(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	id, err := noteID(r)
+	if err != nil {
+		return err
+	}
+
+	revision, err := revisionNumber(r.PathValue("rev"), "revision")
+	if err != nil {
+		return err
+	}
+
+	version, err := ctrl.ifMatch(r, id, userID)
+	if err != nil {
+		return err
+	}
+
+	note, err := ctrl.notes.RestoreRevision(r.Context(), id, userID, revision, version)
+	if err != nil {
+		logger.Error("failed to restore revision", log.Err(err))
+		return err
+	}
+
+	setETag(w, note.Version)
+	_ = encoding.Encode(http.StatusOK, w, newNoteResponse(note))
+	return nil
+}
+
+func newRevisionResponse(rev entity.NoteRevision) *revisionResponse {
+	return &revisionResponse{
+		Revision:  rev.Revision,
+		Title:     rev.Title,
+		Body:      rev.Body,
+		Tags:      rev.Tags,
+		CreatedAt: rev.CreatedAt,
+	}
+}
+
+func revisionNumber(s, name string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, httperror.WithStatusError(fmt.Errorf("invalid %s", name), http.StatusBadRequest)
+	}
+	return n, nil
+}
@@ -0,0 +1,102 @@
+package note
+
+import (
+	"errors"
+
+	"github.com/bojackodin/notes/internal/http/httperror"
+	"github.com/bojackodin/notes/internal/service"
+)
+
+type misspellResponse struct {
+	Field string `json:"field"`
+	// Code is one of unknown_word, repeated_word, capitalization and
+	// too_many_errors.
+	Code string `json:"code"`
+	// Pos, Row, Col and Len locate the misspelling within the field, in
+	// characters counted from zero.
+	Pos         int      `json:"pos"`
+	Row         int      `json:"row"`
+	Col         int      `json:"col"`
+	Len         int      `json:"len"`
+	Word        string   `json:"word"`
+	Suggestions []string `json:"suggestions"`
+}
+
+type correctionResponse struct {
+	Field string `json:"field"`
+	// Pos, Row, Col and Len locate the corrected word in the submitted
+	// text.
+	Pos         int    `json:"pos"`
+	Row         int    `json:"row"`
+	Col         int    `json:"col"`
+	Len         int    `json:"len"`
+	Word        string `json:"word"`
+	Replacement string `json:"replacement"`
+}
+
+// spellingResponse reports the misspellings saved as is under the warn
+// policy, and the ones replaced under the autocorrect policy.
+type spellingResponse struct {
+	Warnings    []misspellResponse   `json:"warnings,omitempty"`
+	Corrections []correctionResponse `json:"corrections,omitempty"`
+}
+
+func newSpellingResponse(check service.SpellCheck) spellingResponse {
+	var response spellingResponse
+
+	for _, field := range check.Warnings {
+		response.Warnings = append(response.Warnings, newMisspellResponses(field)...)
+	}
+	for _, c := range check.Corrections {
+		response.Corrections = append(response.Corrections, correctionResponse{
+			Field:       c.Field,
+			Pos:         c.Misspell.Pos,
+			Row:         c.Misspell.Row,
+			Col:         c.Misspell.Col,
+			Len:         c.Misspell.Len,
+			Word:        c.Misspell.Word,
+			Replacement: c.Replacement,
+		})
+	}
+
+	return response
+}
+
+func newMisspellResponses(field service.FieldMisspells) []misspellResponse {
+	responses := make([]misspellResponse, 0, len(field.Misspells))
+	for _, m := range field.Misspells {
+		suggestions := m.Suggestions
+		if suggestions == nil {
+			suggestions = []string{}
+		}
+
+		responses = append(responses, misspellResponse{
+			Field:       field.Field,
+			Code:        m.Code.String(),
+			Pos:         m.Pos,
+			Row:         m.Row,
+			Col:         m.Col,
+			Len:         m.Len,
+			Word:        m.Word,
+			Suggestions: suggestions,
+		})
+	}
+
+	return responses
+}
+
+// withMisspells attaches every misspelling of a *service.SpellError to err,
+// so that clients can highlight and fix them.
+func withMisspells(err error) error {
+	var spellErr *service.SpellError
+	if !errors.As(err, &spellErr) {
+		return err
+	}
+
+	details := make([]misspellResponse, 0)
+	for _, field := range spellErr.Fields {
+		details = append(details, newMisspellResponses(field)...)
+	}
+
+	return httperror.WithDetails(err, details)
+}
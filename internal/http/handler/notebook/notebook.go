@@ -0,0 +1,206 @@
+package notebook
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/http/encoding"
+	contexthelper "github.com/bojackodin/notes/internal/http/handler/context"
+	"github.com/bojackodin/notes/internal/http/httperror"
+	"github.com/bojackodin/notes/internal/log"
+	"github.com/bojackodin/notes/internal/service"
+)
+
+type Controller struct {
+	notebooks service.Notebook
+}
+
+func New(notebooks service.Notebook) *Controller {
+	return &Controller{
+		notebooks: notebooks,
+	}
+}
+
+type notebookResponse struct {
+	ID        int64     `json:"id"`
+	ParentID  *int64    `json:"parent_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newNotebookResponse(notebook entity.Notebook) *notebookResponse {
+	return &notebookResponse{
+		ID:        notebook.ID,
+		ParentID:  notebook.ParentID,
+		Name:      notebook.Name,
+		CreatedAt: notebook.CreatedAt,
+		UpdatedAt: notebook.UpdatedAt,
+	}
+}
+
+type createNotebookInput struct {
+	Name     string `json:"name"`
+	ParentID *int64 `json:"parent_id"`
+}
+
+func (ctrl *Controller) CreateNotebook(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	var input createNotebookInput
+	if err := encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	notebook, err := ctrl.notebooks.CreateNotebook(r.Context(), userID, input.Name, input.ParentID)
+	if err != nil {
+		logger.Error("failed to create notebook", log.Err(err))
+		return err
+	}
+
+	_ = encoding.Encode(http.StatusCreated, w, newNotebookResponse(notebook))
+	return nil
+}
+
+func (ctrl *Controller) GetNotebook(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	id, err := notebookID(r)
+	if err != nil {
+		return err
+	}
+
+	notebook, err := ctrl.notebooks.GetNotebook(r.Context(), id, userID)
+	if err != nil {
+		logger.Error("failed to get notebook", log.Err(err))
+		return err
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, newNotebookResponse(notebook))
+	return nil
+}
+
+type listNotebooksResponse []*notebookResponse
+
+func (ctrl *Controller) ListNotebooks(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	notebooks, err := ctrl.notebooks.ListNotebooks(r.Context(), userID)
+	if err != nil {
+		logger.Error("failed to list notebooks", log.Err(err))
+		return err
+	}
+
+	response := make(listNotebooksResponse, 0, len(notebooks))
+	for _, notebook := range notebooks {
+		response = append(response, newNotebookResponse(notebook))
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, &response)
+
+	return nil
+}
+
+type renameNotebookInput struct {
+	Name string `json:"name"`
+}
+
+func (ctrl *Controller) RenameNotebook(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	id, err := notebookID(r)
+	if err != nil {
+		return err
+	}
+
+	var input renameNotebookInput
+	if err := encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	notebook, err := ctrl.notebooks.RenameNotebook(r.Context(), id, userID, input.Name)
+	if err != nil {
+		logger.Error("failed to rename notebook", log.Err(err))
+		return err
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, newNotebookResponse(notebook))
+	return nil
+}
+
+type moveNotebookInput struct {
+	ParentID *int64 `json:"parent_id"`
+}
+
+// MoveNotebook changes the parent notebook. A null parent_id makes the
+// notebook a top-level one.
+func (ctrl *Controller) MoveNotebook(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	id, err := notebookID(r)
+	if err != nil {
+		return err
+	}
+
+	var input moveNotebookInput
+	if err := encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	notebook, err := ctrl.notebooks.MoveNotebook(r.Context(), id, userID, input.ParentID)
+	if err != nil {
+		logger.Error("failed to move notebook", log.Err(err))
+		return err
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, newNotebookResponse(notebook))
+	return nil
+}
+
+// DeleteNotebook deletes the notebook. With ?cascade=true its child
+// notebooks and notes are deleted too; otherwise they are moved to the
+// parent notebook.
+func (ctrl *Controller) DeleteNotebook(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	id, err := notebookID(r)
+	if err != nil {
+		return err
+	}
+
+	var cascade bool
+	if v := r.URL.Query().Get("cascade"); v != "" {
+		cascade, err = strconv.ParseBool(v)
+		if err != nil {
+			return httperror.WithStatusError(errors.New("invalid cascade"), http.StatusBadRequest)
+		}
+	}
+
+	if err = ctrl.notebooks.DeleteNotebook(r.Context(), id, userID, cascade); err != nil {
+		logger.Error("failed to delete notebook", log.Err(err))
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func notebookID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, httperror.WithStatusError(errors.New("invalid notebook id"), http.StatusBadRequest)
+	}
+	return id, nil
+}
@@ -0,0 +1,115 @@
+package password
+
+import (
+	"net/http"
+
+	"github.com/bojackodin/notes/internal/http/encoding"
+	contexthelper "github.com/bojackodin/notes/internal/http/handler/context"
+	"github.com/bojackodin/notes/internal/http/httperror"
+	"github.com/bojackodin/notes/internal/log"
+	"github.com/bojackodin/notes/internal/service"
+)
+
+type Controller struct {
+	passwords      service.Password
+	clientIPHeader string
+}
+
+func New(passwords service.Password, optFns ...OptionFn) *Controller {
+	options := &options{}
+	for _, fn := range optFns {
+		fn(options)
+	}
+
+	return &Controller{
+		passwords:      passwords,
+		clientIPHeader: options.clientIPHeader,
+	}
+}
+
+type options struct {
+	clientIPHeader string
+}
+
+type OptionFn func(*options)
+
+// WithClientIPHeader takes the client IP from the given header, such as
+// X-Real-IP, set by a trusted reverse proxy.
+func WithClientIPHeader(header string) OptionFn {
+	return func(o *options) {
+		o.clientIPHeader = header
+	}
+}
+
+type changePasswordInput struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+func (ctrl *Controller) ChangePassword(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+	sessionID := contexthelper.ContextGetSessionID(r)
+
+	var input changePasswordInput
+	if err := encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	err := ctrl.passwords.ChangePassword(r.Context(), userID, sessionID, input.OldPassword, input.NewPassword)
+	if err != nil {
+		logger.Error("failed to change password", log.Err(err))
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type requestResetInput struct {
+	Username string `json:"username"`
+}
+
+// RequestReset always responds with 202 Accepted, whether the user exists or
+// not, unless requests are throttled.
+func (ctrl *Controller) RequestReset(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+
+	var input requestResetInput
+	if err := encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	if err := ctrl.passwords.RequestPasswordReset(r.Context(), input.Username, contexthelper.ClientIP(r, ctrl.clientIPHeader)); err != nil {
+		logger.Error("failed to request password reset", log.Err(err))
+		return err
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	return nil
+}
+
+type confirmResetInput struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func (ctrl *Controller) ConfirmReset(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+
+	var input confirmResetInput
+	if err := encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	if err := ctrl.passwords.ResetPassword(r.Context(), input.Token, input.NewPassword); err != nil {
+		logger.Error("failed to reset password", log.Err(err))
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
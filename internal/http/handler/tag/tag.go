@@ -0,0 +1,50 @@
+package tag
+
+import (
+	"net/http"
+
+	"github.com/bojackodin/notes/internal/http/encoding"
+	contexthelper "github.com/bojackodin/notes/internal/http/handler/context"
+	"github.com/bojackodin/notes/internal/log"
+	"github.com/bojackodin/notes/internal/service"
+)
+
+type Controller struct {
+	tags service.Tag
+}
+
+func New(tags service.Tag) *Controller {
+	return &Controller{
+		tags: tags,
+	}
+}
+
+type tagResponse struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type listTagsResponse []*tagResponse
+
+func (ctrl *Controller) ListTags(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	tags, err := ctrl.tags.ListTags(r.Context(), userID)
+	if err != nil {
+		logger.Error("failed to list tags", log.Err(err))
+		return err
+	}
+
+	response := make(listTagsResponse, 0, len(tags))
+	for _, tag := range tags {
+		response = append(response, &tagResponse{
+			Name:  tag.Name,
+			Count: tag.NoteCount,
+		})
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, &response)
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package twofactor
+
+import (
+	"net/http"
+
+	"github.com/bojackodin/notes/internal/http/encoding"
+	contexthelper "github.com/bojackodin/notes/internal/http/handler/context"
+	"github.com/bojackodin/notes/internal/http/httperror"
+	"github.com/bojackodin/notes/internal/log"
+	"github.com/bojackodin/notes/internal/service"
+)
+
+type Controller struct {
+	twoFactor service.TwoFactor
+}
+
+func New(twoFactor service.TwoFactor) *Controller {
+	return &Controller{
+		twoFactor: twoFactor,
+	}
+}
+
+type enrollResponse struct {
+	Secret        string   `json:"secret"`
+	URI           string   `json:"otpauth_uri"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (ctrl *Controller) Enroll(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	enrollment, err := ctrl.twoFactor.EnrollTwoFactor(r.Context(), userID)
+	if err != nil {
+		logger.Error("failed to enroll two-factor authentication", log.Err(err))
+		return err
+	}
+
+	_ = encoding.Encode(http.StatusCreated, w, &enrollResponse{
+		Secret:        enrollment.Secret,
+		URI:           enrollment.URI,
+		RecoveryCodes: enrollment.RecoveryCodes,
+	})
+	return nil
+}
+
+type confirmInput struct {
+	Code string `json:"code"`
+}
+
+func (ctrl *Controller) Confirm(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	var input confirmInput
+	if err := encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	if err := ctrl.twoFactor.ConfirmTwoFactor(r.Context(), userID, input.Code); err != nil {
+		logger.Error("failed to confirm two-factor authentication", log.Err(err))
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type disableInput struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (ctrl *Controller) Disable(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	var input disableInput
+	if err := encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	if err := ctrl.twoFactor.DisableTwoFactor(r.Context(), userID, input.Password, input.Code, input.RecoveryCode); err != nil {
+		logger.Error("failed to disable two-factor authentication", log.Err(err))
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
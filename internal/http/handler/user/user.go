@@ -0,0 +1,141 @@
+package user
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/http/encoding"
+	contexthelper "github.com/bojackodin/notes/internal/http/handler/context"
+	"github.com/bojackodin/notes/internal/http/httperror"
+	"github.com/bojackodin/notes/internal/log"
+	"github.com/bojackodin/notes/internal/service"
+)
+
+type Controller struct {
+	users service.User
+}
+
+func New(users service.User) *Controller {
+	return &Controller{
+		users: users,
+	}
+}
+
+type userResponse struct {
+	ID        int64       `json:"id"`
+	Username  string      `json:"username"`
+	Role      entity.Role `json:"role"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+func newUserResponse(user entity.User) *userResponse {
+	return &userResponse{
+		ID:        user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+func (ctrl *Controller) Me(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	user, err := ctrl.users.GetUser(r.Context(), userID)
+	if err != nil {
+		logger.Error("failed to get user", log.Err(err))
+		return err
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, newUserResponse(user))
+	return nil
+}
+
+type updateMeInput struct {
+	Username string `json:"username"`
+}
+
+func (ctrl *Controller) UpdateMe(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	var input updateMeInput
+	if err := encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	user, err := ctrl.users.UpdateUsername(r.Context(), userID, input.Username)
+	if err != nil {
+		logger.Error("failed to update user", log.Err(err))
+		return err
+	}
+
+	_ = encoding.Encode(http.StatusOK, w, newUserResponse(user))
+	return nil
+}
+
+type deleteMeInput struct {
+	Password string `json:"password"`
+}
+
+func (ctrl *Controller) DeleteMe(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	var input deleteMeInput
+	if err := encoding.Decode(r, &input); err != nil {
+		logger.Error("failed to decode body", log.Err(err))
+		return httperror.WithStatusError(err, http.StatusBadRequest)
+	}
+
+	if err := ctrl.users.DeleteUser(r.Context(), userID, input.Password); err != nil {
+		logger.Error("failed to delete user", log.Err(err))
+		return err
+	}
+
+	logger.Info("user deleted", "user_id", userID)
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// Export streams a ZIP archive of the user's notes. Errors before anything
+// is written, such as a failed query, are reported as usual. Once streaming
+// has begun they can no longer be, so the archive is left truncated.
+func (ctrl *Controller) Export(w http.ResponseWriter, r *http.Request) error {
+	logger := log.FromContext(r.Context())
+	userID := contexthelper.ContextGetUserID(r)
+
+	filename := "notes-export-" + time.Now().UTC().Format("20060102") + ".zip"
+	ew := &exportWriter{w: w, filename: filename}
+
+	if err := ctrl.users.ExportUser(r.Context(), userID, ew); err != nil {
+		logger.Error("failed to export user", log.Err(err))
+		if !ew.written {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exportWriter sets the archive headers on the first write, so that a
+// failure before it is still answered with an error response.
+type exportWriter struct {
+	w        http.ResponseWriter
+	filename string
+	written  bool
+}
+
+func (ew *exportWriter) Write(p []byte) (int, error) {
+	if !ew.written {
+		ew.written = true
+		ew.w.Header().Set("Content-Type", "application/zip")
+		ew.w.Header().Set("Content-Disposition", `attachment; filename="`+ew.filename+`"`)
+		ew.w.Header().Set("Cache-Control", "no-store")
+	}
+
+	return ew.w.Write(p)
+}
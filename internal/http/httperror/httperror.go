@@ -34,26 +34,58 @@ func WithStatusError(err error, status int) error {
 	}
 }
 
-func HTTPStatus(err error) int {
-	if err == nil {
-		return 0
-	}
-
+// HTTPStatus returns the status attached to err by WithStatus or
+// WithStatusError, and false if there is none.
+func HTTPStatus(err error) (int, bool) {
 	var statusErr statusError
 	if errors.As(err, &statusErr) {
-		return statusErr.status
+		return statusErr.status, true
+	}
+	return 0, false
+}
+
+type detailsError struct {
+	err     error
+	details any
+}
+
+func (e detailsError) Error() string { return e.err.Error() }
+
+func (e detailsError) Unwrap() error { return e.err }
+
+// WithDetails attaches structured details, responded with the error, to err.
+func WithDetails(err error, details any) error {
+	return detailsError{
+		err:     err,
+		details: details,
 	}
-	return http.StatusInternalServerError
+}
+
+// Details returns the details attached to err by WithDetails, and false if
+// there are none.
+func Details(err error) (any, bool) {
+	var detailsErr detailsError
+	if errors.As(err, &detailsErr) {
+		return detailsErr.details, true
+	}
+	return nil, false
 }
 
 type errorResponse struct {
-	Error string `json:"error,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Details any    `json:"details,omitempty"`
 }
 
 func RespondWithError(w http.ResponseWriter, err string, code int) {
+	RespondWithDetails(w, err, nil, code)
+}
+
+// RespondWithDetails responds with the error message and structured details,
+// such as the rejected input fields.
+func RespondWithDetails(w http.ResponseWriter, err string, details any, code int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
-	if err != "" {
-		_ = json.NewEncoder(w).Encode(&errorResponse{Error: err})
+	if err != "" || details != nil {
+		_ = json.NewEncoder(w).Encode(&errorResponse{Error: err, Details: details})
 	}
 }
@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogNotifier writes messages to the log. It is meant for local use only,
+// as the messages may contain secrets.
+type LogNotifier struct {
+	logger *slog.Logger
+}
+
+func NewLogNotifier(logger *slog.Logger) *LogNotifier {
+	return &LogNotifier{
+		logger: logger,
+	}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, msg Message) error {
+	n.logger.LogAttrs(ctx, slog.LevelInfo, "notification", slog.Group("message",
+		slog.Int64("user_id", msg.UserID),
+		slog.String("username", msg.Username),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body),
+	))
+	return nil
+}
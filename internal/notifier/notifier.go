@@ -0,0 +1,16 @@
+// Package notifier delivers messages to users.
+package notifier
+
+import "context"
+
+// Message is a notification addressed to a user.
+type Message struct {
+	UserID   int64
+	Username string
+	Subject  string
+	Body     string
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
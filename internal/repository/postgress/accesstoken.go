@@ -0,0 +1,112 @@
+package postgress
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/repository/repositoryerror"
+
+	"github.com/lib/pq"
+)
+
+type AccessTokenRepository struct {
+	client *sql.DB
+}
+
+func NewAccessTokenRepository(client *sql.DB) *AccessTokenRepository {
+	return &AccessTokenRepository{
+		client: client,
+	}
+}
+
+const accessTokenColumns = `id, user_id, name, scopes, expires_at, last_used_at, created_at`
+
+func scanAccessToken(row interface{ Scan(...any) error }, token *entity.AccessToken) error {
+	return row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		pq.Array(&token.Scopes),
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.CreatedAt,
+	)
+}
+
+func (db *AccessTokenRepository) CreateAccessToken(ctx context.Context, token *entity.AccessToken, tokenHash []byte) error {
+	query := `
+		INSERT INTO access_tokens (user_id, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return db.client.QueryRowContext(ctx, query, token.UserID, token.Name, tokenHash, pq.Array(token.Scopes), token.ExpiresAt).Scan(
+		&token.ID,
+		&token.CreatedAt,
+	)
+}
+
+func (db *AccessTokenRepository) ListAccessTokens(ctx context.Context, userID int64) ([]entity.AccessToken, error) {
+	query := `
+		SELECT ` + accessTokenColumns + `
+		FROM access_tokens
+		WHERE user_id = $1
+		ORDER BY id`
+
+	rows, err := db.client.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]entity.AccessToken, 0)
+
+	for rows.Next() {
+		var token entity.AccessToken
+		if err = scanAccessToken(rows, &token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (db *AccessTokenRepository) DeleteAccessToken(ctx context.Context, id, userID int64) error {
+	query := `
+		DELETE FROM access_tokens
+		WHERE id = $1 AND user_id = $2`
+
+	result, err := db.client.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+// UseAccessToken looks up an unexpired token of an enabled user by its hash
+// and records its use.
+func (db *AccessTokenRepository) UseAccessToken(ctx context.Context, tokenHash []byte) (entity.AccessToken, error) {
+	query := `
+		UPDATE access_tokens
+		SET last_used_at = NOW()
+		WHERE token_hash = $1 AND (expires_at IS NULL OR expires_at > NOW())
+			AND user_id IN (SELECT id FROM users WHERE disabled_at IS NULL)
+		RETURNING ` + accessTokenColumns
+
+	var token entity.AccessToken
+
+	err := scanAccessToken(db.client.QueryRowContext(ctx, query, tokenHash), &token)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return entity.AccessToken{}, repositoryerror.ErrRecordNotFound
+		default:
+			return entity.AccessToken{}, err
+		}
+	}
+
+	return token, nil
+}
@@ -3,8 +3,14 @@ package postgress
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/repository/repositoryerror"
+	"github.com/lib/pq"
 )
 
 type NoteRepository struct {
@@ -17,22 +23,431 @@ func NewNoteRepository(client *sql.DB) *NoteRepository {
 	}
 }
 
+// noteColumns are the columns scanned by scanNote, qualified by the notes table.
+const noteColumns = `notes.id, notes.user_id, notes.notebook_id, notes.title, notes.body,
+			ARRAY(
+				SELECT tags.name
+				FROM note_tags JOIN tags ON tags.id = note_tags.tag_id
+				WHERE note_tags.note_id = notes.id
+				ORDER BY tags.name
+			),
+			notes.version, notes.created_at, notes.updated_at, notes.deleted_at`
+
+func scanNote(row interface{ Scan(...any) error }, note *entity.Note, extra ...any) error {
+	dest := []any{
+		&note.ID,
+		&note.UserID,
+		&note.NotebookID,
+		&note.Title,
+		&note.Body,
+		pq.Array(&note.Tags),
+		&note.Version,
+		&note.CreatedAt,
+		&note.UpdatedAt,
+		&note.DeletedAt,
+	}
+
+	return row.Scan(append(dest, extra...)...)
+}
+
 func (db *NoteRepository) CreateNote(ctx context.Context, note *entity.Note) error {
+	return inTx(ctx, db.client, func(tx *sql.Tx) error {
+		query := `
+			INSERT INTO notes (user_id, notebook_id, title, body)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, version, created_at, updated_at`
+
+		err := tx.QueryRowContext(ctx, query, note.UserID, note.NotebookID, note.Title, note.Body).Scan(
+			&note.ID,
+			&note.Version,
+			&note.CreatedAt,
+			&note.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err = setNoteTags(ctx, tx, note); err != nil {
+			return err
+		}
+
+		return insertRevision(ctx, tx, note)
+	})
+}
+
+func (db *NoteRepository) GetNote(ctx context.Context, id, userID int64) (entity.Note, error) {
+	query := `
+		SELECT ` + noteColumns + `
+		FROM notes
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
+
+	var note entity.Note
+
+	err := scanNote(db.client.QueryRowContext(ctx, query, id, userID), &note)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return entity.Note{}, repositoryerror.ErrRecordNotFound
+		default:
+			return entity.Note{}, err
+		}
+	}
+
+	return note, nil
+}
+
+// UpdateNote saves the note if its stored version still equals note.Version,
+// and increments the version. It returns repositoryerror.ErrConflict if the
+// note has been changed concurrently.
+func (db *NoteRepository) UpdateNote(ctx context.Context, note *entity.Note) error {
+	return inTx(ctx, db.client, func(tx *sql.Tx) error {
+		query := `
+			UPDATE notes
+			SET title = $1, body = $2, version = version + 1, updated_at = NOW()
+			WHERE id = $3 AND user_id = $4 AND deleted_at IS NULL AND version = $5
+			RETURNING version, updated_at`
+
+		err := tx.QueryRowContext(ctx, query, note.Title, note.Body, note.ID, note.UserID, note.Version).Scan(
+			&note.Version,
+			&note.UpdatedAt,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return missingNoteError(ctx, tx, note.ID, note.UserID)
+			default:
+				return err
+			}
+		}
+
+		if err = setNoteTags(ctx, tx, note); err != nil {
+			return err
+		}
+
+		return insertRevision(ctx, tx, note)
+	})
+}
+
+// missingNoteError tells apart a missing note from one whose version has
+// changed after a conditional write affected no rows.
+func missingNoteError(ctx context.Context, q interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}, id, userID int64) error {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM notes
+			WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+		)`
+
+	var exists bool
+	if err := q.QueryRowContext(ctx, query, id, userID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return repositoryerror.ErrConflict
+	}
+	return repositoryerror.ErrRecordNotFound
+}
+
+// insertRevision snapshots the note as its next revision.
+func insertRevision(ctx context.Context, tx *sql.Tx, note *entity.Note) error {
+	query := `
+		INSERT INTO note_revisions (note_id, revision, title, body, tags)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, COALESCE($4::text[], '{}')
+		FROM note_revisions
+		WHERE note_id = $1`
+
+	_, err := tx.ExecContext(ctx, query, note.ID, note.Title, note.Body, pq.Array(note.Tags))
+	return err
+}
+
+func (db *NoteRepository) ListRevisions(ctx context.Context, noteID, userID int64) ([]entity.NoteRevision, error) {
+	query := `
+		SELECT note_revisions.note_id, note_revisions.revision, note_revisions.title,
+			note_revisions.body, note_revisions.tags, note_revisions.created_at
+		FROM note_revisions JOIN notes ON notes.id = note_revisions.note_id
+		WHERE note_revisions.note_id = $1 AND notes.user_id = $2 AND notes.deleted_at IS NULL
+		ORDER BY note_revisions.revision DESC`
+
+	rows, err := db.client.QueryContext(ctx, query, noteID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]entity.NoteRevision, 0)
+
+	for rows.Next() {
+		var revision entity.NoteRevision
+
+		err := rows.Scan(
+			&revision.NoteID,
+			&revision.Revision,
+			&revision.Title,
+			&revision.Body,
+			pq.Array(&revision.Tags),
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (db *NoteRepository) GetRevision(ctx context.Context, noteID, userID int64, revision int) (entity.NoteRevision, error) {
+	query := `
+		SELECT note_revisions.note_id, note_revisions.revision, note_revisions.title,
+			note_revisions.body, note_revisions.tags, note_revisions.created_at
+		FROM note_revisions JOIN notes ON notes.id = note_revisions.note_id
+		WHERE note_revisions.note_id = $1 AND notes.user_id = $2 AND notes.deleted_at IS NULL
+			AND note_revisions.revision = $3`
+
+	var rev entity.NoteRevision
+
+	err := db.client.QueryRowContext(ctx, query, noteID, userID, revision).Scan(
+		&rev.NoteID,
+		&rev.Revision,
+		&rev.Title,
+		&rev.Body,
+		pq.Array(&rev.Tags),
+		&rev.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return entity.NoteRevision{}, repositoryerror.ErrRecordNotFound
+		default:
+			return entity.NoteRevision{}, err
+		}
+	}
+
+	return rev, nil
+}
+
+// MoveNote changes the notebook of the note under the same version check as UpdateNote.
+func (db *NoteRepository) MoveNote(ctx context.Context, note *entity.Note) error {
+	query := `
+		UPDATE notes
+		SET notebook_id = $1, version = version + 1, updated_at = NOW()
+		WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL AND version = $4
+		RETURNING version, updated_at`
+
+	err := db.client.QueryRowContext(ctx, query, note.NotebookID, note.ID, note.UserID, note.Version).Scan(
+		&note.Version,
+		&note.UpdatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return missingNoteError(ctx, db.client, note.ID, note.UserID)
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setNoteTags replaces the tags of the note with note.Tags, creating missing
+// tags and removing the ones no longer attached to any note.
+func setNoteTags(ctx context.Context, tx *sql.Tx, note *entity.Note) error {
+	queries := []struct {
+		query string
+		args  []any
+	}{
+		{`
+			DELETE FROM note_tags
+			WHERE note_id = $1`,
+			[]any{note.ID},
+		},
+		{`
+			INSERT INTO tags (user_id, name)
+			SELECT $1, unnest($2::text[])
+			ON CONFLICT (user_id, name) DO NOTHING`,
+			[]any{note.UserID, pq.Array(note.Tags)},
+		},
+		{`
+			INSERT INTO note_tags (note_id, tag_id)
+			SELECT $1, id
+			FROM tags
+			WHERE user_id = $2 AND name = ANY($3)`,
+			[]any{note.ID, note.UserID, pq.Array(note.Tags)},
+		},
+		{`
+			DELETE FROM tags
+			WHERE user_id = $1 AND NOT EXISTS (
+				SELECT 1 FROM note_tags WHERE note_tags.tag_id = tags.id
+			)`,
+			[]any{note.UserID},
+		},
+	}
+
+	for _, q := range queries {
+		if _, err := tx.ExecContext(ctx, q.query, q.args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteNote moves the note to the trash if its stored version equals version.
+func (db *NoteRepository) DeleteNote(ctx context.Context, id, userID int64, version int) error {
+	query := `
+		UPDATE notes
+		SET deleted_at = NOW(), version = version + 1
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND version = $3`
+
+	result, err := db.client.ExecContext(ctx, query, id, userID, version)
+	if err != nil {
+		return err
+	}
+
+	if err = checkAffected(result); err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return missingNoteError(ctx, db.client, id, userID)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (db *NoteRepository) RestoreNote(ctx context.Context, id, userID int64) error {
 	query := `
-		INSERT INTO notes (user_id, title)
-		VALUES ($1, $2)
-		RETURNING id`
+		UPDATE notes
+		SET deleted_at = NULL, version = version + 1
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL`
 
-	return db.client.QueryRowContext(ctx, query, note.UserID, note.Title).Scan(&note.ID)
+	result, err := db.client.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
 }
 
-func (db *NoteRepository) ListNotes(ctx context.Context, userID int64) ([]entity.Note, error) {
+// EmptyTrash permanently removes the trashed notes of the user.
+func (db *NoteRepository) EmptyTrash(ctx context.Context, userID int64) (int64, error) {
 	query := `
-		SELECT id, user_id, title
+		DELETE FROM notes
+		WHERE user_id = $1 AND deleted_at IS NOT NULL`
+
+	result, err := db.client.ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// PurgeDeletedNotes permanently removes notes trashed longer than retention ago.
+func (db *NoteRepository) PurgeDeletedNotes(ctx context.Context, retention time.Duration) (int64, error) {
+	query := `
+		DELETE FROM notes
+		WHERE deleted_at < NOW() - make_interval(secs => $1)`
+
+	result, err := db.client.ExecContext(ctx, query, retention.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+var noteSortColumns = map[entity.NoteSortField]string{
+	entity.NoteSortID:      "id",
+	entity.NoteSortCreated: "created_at",
+	entity.NoteSortUpdated: "updated_at",
+	entity.NoteSortTitle:   "title",
+}
+
+func (db *NoteRepository) ListNotes(ctx context.Context, filter entity.NoteFilter) ([]entity.Note, error) {
+	column, ok := noteSortColumns[filter.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field %q", filter.Sort)
+	}
+
+	var args queryArgs
+	conditions := []string{"user_id = " + args.add(filter.UserID)}
+
+	if filter.Deleted {
+		conditions = append(conditions, "deleted_at IS NOT NULL")
+	} else {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	if filter.NotebookID != nil {
+		conditions = append(conditions, "notebook_id = "+args.add(*filter.NotebookID))
+	}
+
+	if filter.Title != "" {
+		conditions = append(conditions, "title ILIKE "+args.add(containsPattern(filter.Title)))
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at >= "+args.add(filter.CreatedAfter))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at < "+args.add(filter.CreatedBefore))
+	}
+	if len(filter.Tags) > 0 {
+		tagged := `
+			SELECT count(DISTINCT tags.name)
+			FROM note_tags JOIN tags ON tags.id = note_tags.tag_id
+			WHERE note_tags.note_id = notes.id AND tags.name = ANY(` + args.add(pq.Array(filter.Tags)) + `)`
+
+		if filter.TagsMatchAny {
+			conditions = append(conditions, "("+tagged+") > 0")
+		} else {
+			conditions = append(conditions, "("+tagged+") = "+args.add(len(filter.Tags)))
+		}
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if after := filter.After; after != nil {
+		var value any
+		switch filter.Sort {
+		case entity.NoteSortCreated:
+			value = after.CreatedAt
+		case entity.NoteSortUpdated:
+			value = after.UpdatedAt
+		case entity.NoteSortTitle:
+			value = after.Title
+		}
+
+		if value == nil {
+			conditions = append(conditions, "id "+comparison+" "+args.add(after.ID))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s, %s)",
+				column, comparison, args.add(value), args.add(after.ID)))
+		}
+	}
+
+	order := "id " + direction
+	if column != "id" {
+		order = column + " " + direction + ", " + order
+	}
+
+	query := `
+		SELECT ` + noteColumns + `
 		FROM notes
-		WHERE user_id = $1`
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY ` + order + `
+		LIMIT ` + args.add(filter.Limit)
 
-	rows, err := db.client.QueryContext(ctx, query, userID)
+	rows, err := db.client.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -43,11 +458,7 @@ func (db *NoteRepository) ListNotes(ctx context.Context, userID int64) ([]entity
 	for rows.Next() {
 		var note entity.Note
 
-		err := rows.Scan(
-			&note.ID,
-			&note.UserID,
-			&note.Title,
-		)
+		err := scanNote(rows, &note)
 		if err != nil {
 			return nil, err
 		}
@@ -61,3 +472,86 @@ func (db *NoteRepository) ListNotes(ctx context.Context, userID int64) ([]entity
 
 	return notes, nil
 }
+
+// ExportNotes calls fn for every note of the user, trashed ones included,
+// ordered by ID. The notes are streamed rather than loaded at once.
+func (db *NoteRepository) ExportNotes(ctx context.Context, userID int64, fn func(entity.Note) error) error {
+	query := `
+		SELECT ` + noteColumns + `
+		FROM notes
+		WHERE user_id = $1
+		ORDER BY id`
+
+	rows, err := db.client.QueryContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var note entity.Note
+
+		if err = scanNote(rows, &note); err != nil {
+			return err
+		}
+
+		if err = fn(note); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (db *NoteRepository) SearchNotes(ctx context.Context, search entity.NoteSearch) ([]entity.NoteSearchResult, error) {
+	query := `
+		SELECT ` + noteColumns + `,
+			ts_rank_cd(search_vector, query) AS rank,
+			ts_headline($2::regconfig, title, query, 'HighlightAll=true'),
+			ts_headline($2::regconfig, body, query, 'MaxFragments=3, MaxWords=30, MinWords=10')
+		FROM notes, websearch_to_tsquery($2::regconfig, $3) AS query
+		WHERE user_id = $1 AND deleted_at IS NULL AND search_vector @@ query
+		ORDER BY rank DESC, id DESC
+		LIMIT $4 OFFSET $5`
+
+	rows, err := db.client.QueryContext(ctx, query,
+		search.UserID, string(search.Language), search.Query, search.Limit, search.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]entity.NoteSearchResult, 0)
+
+	for rows.Next() {
+		var result entity.NoteSearchResult
+
+		err := scanNote(rows, &result.Note,
+			&result.Rank,
+			&result.TitleHighlight,
+			&result.BodySnippet,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func checkAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repositoryerror.ErrRecordNotFound
+	}
+	return nil
+}
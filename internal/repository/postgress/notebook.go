@@ -0,0 +1,238 @@
+package postgress
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/repository/repositoryerror"
+)
+
+type NotebookRepository struct {
+	client *sql.DB
+}
+
+func NewNotebookRepository(client *sql.DB) *NotebookRepository {
+	return &NotebookRepository{
+		client: client,
+	}
+}
+
+func (db *NotebookRepository) CreateNotebook(ctx context.Context, notebook *entity.Notebook) error {
+	query := `
+		INSERT INTO notebooks (user_id, parent_id, name)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at`
+
+	return db.client.QueryRowContext(ctx, query, notebook.UserID, notebook.ParentID, notebook.Name).Scan(
+		&notebook.ID,
+		&notebook.CreatedAt,
+		&notebook.UpdatedAt,
+	)
+}
+
+func (db *NotebookRepository) GetNotebook(ctx context.Context, id, userID int64) (entity.Notebook, error) {
+	query := `
+		SELECT id, user_id, parent_id, name, created_at, updated_at
+		FROM notebooks
+		WHERE id = $1 AND user_id = $2`
+
+	var notebook entity.Notebook
+
+	err := db.client.QueryRowContext(ctx, query, id, userID).Scan(
+		&notebook.ID,
+		&notebook.UserID,
+		&notebook.ParentID,
+		&notebook.Name,
+		&notebook.CreatedAt,
+		&notebook.UpdatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return entity.Notebook{}, repositoryerror.ErrRecordNotFound
+		default:
+			return entity.Notebook{}, err
+		}
+	}
+
+	return notebook, nil
+}
+
+func (db *NotebookRepository) ListNotebooks(ctx context.Context, userID int64) ([]entity.Notebook, error) {
+	query := `
+		SELECT id, user_id, parent_id, name, created_at, updated_at
+		FROM notebooks
+		WHERE user_id = $1
+		ORDER BY name, id`
+
+	rows, err := db.client.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notebooks := make([]entity.Notebook, 0)
+
+	for rows.Next() {
+		var notebook entity.Notebook
+
+		err := rows.Scan(
+			&notebook.ID,
+			&notebook.UserID,
+			&notebook.ParentID,
+			&notebook.Name,
+			&notebook.CreatedAt,
+			&notebook.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		notebooks = append(notebooks, notebook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notebooks, nil
+}
+
+func (db *NotebookRepository) RenameNotebook(ctx context.Context, notebook *entity.Notebook) error {
+	query := `
+		UPDATE notebooks
+		SET name = $1, updated_at = NOW()
+		WHERE id = $2 AND user_id = $3
+		RETURNING updated_at`
+
+	err := db.client.QueryRowContext(ctx, query, notebook.Name, notebook.ID, notebook.UserID).Scan(&notebook.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return repositoryerror.ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MoveNotebook changes the parent of the notebook. It returns
+// repositoryerror.ErrCycle if the new parent is the notebook itself or one
+// of its descendants.
+func (db *NotebookRepository) MoveNotebook(ctx context.Context, notebook *entity.Notebook) error {
+	return inTx(ctx, db.client, func(tx *sql.Tx) error {
+		if notebook.ParentID != nil {
+			query := `
+				WITH RECURSIVE ancestors AS (
+					SELECT id, parent_id
+					FROM notebooks
+					WHERE id = $1 AND user_id = $2
+					UNION ALL
+					SELECT notebooks.id, notebooks.parent_id
+					FROM notebooks JOIN ancestors ON notebooks.id = ancestors.parent_id
+				)
+				SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $3)`
+
+			var cycle bool
+			err := tx.QueryRowContext(ctx, query, *notebook.ParentID, notebook.UserID, notebook.ID).Scan(&cycle)
+			if err != nil {
+				return err
+			}
+			if cycle {
+				return repositoryerror.ErrCycle
+			}
+		}
+
+		query := `
+			UPDATE notebooks
+			SET parent_id = $1, updated_at = NOW()
+			WHERE id = $2 AND user_id = $3
+			RETURNING updated_at`
+
+		err := tx.QueryRowContext(ctx, query, notebook.ParentID, notebook.ID, notebook.UserID).Scan(&notebook.UpdatedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return repositoryerror.ErrRecordNotFound
+			default:
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// DeleteNotebook removes the notebook. With cascade the whole subtree of
+// notebooks is removed and the notes in it are moved to the trash; otherwise
+// child notebooks and notes are moved up to the parent of the deleted notebook.
+func (db *NotebookRepository) DeleteNotebook(ctx context.Context, id, userID int64, cascade bool) error {
+	return inTx(ctx, db.client, func(tx *sql.Tx) error {
+		query := `
+			SELECT parent_id
+			FROM notebooks
+			WHERE id = $1 AND user_id = $2
+			FOR UPDATE`
+
+		var parentID *int64
+		err := tx.QueryRowContext(ctx, query, id, userID).Scan(&parentID)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return repositoryerror.ErrRecordNotFound
+			default:
+				return err
+			}
+		}
+
+		type statement struct {
+			query string
+			args  []any
+		}
+
+		var statements []statement
+		if cascade {
+			statements = append(statements, statement{`
+				WITH RECURSIVE subtree AS (
+					SELECT id FROM notebooks WHERE id = $1
+					UNION ALL
+					SELECT notebooks.id
+					FROM notebooks JOIN subtree ON notebooks.parent_id = subtree.id
+				)
+				UPDATE notes
+				SET deleted_at = NOW(), version = version + 1
+				WHERE notebook_id IN (SELECT id FROM subtree) AND deleted_at IS NULL`,
+				[]any{id},
+			})
+		} else {
+			statements = append(statements, statement{`
+				UPDATE notes
+				SET notebook_id = $1, version = version + 1, updated_at = NOW()
+				WHERE notebook_id = $2`,
+				[]any{parentID, id},
+			}, statement{`
+				UPDATE notebooks
+				SET parent_id = $1, updated_at = NOW()
+				WHERE parent_id = $2`,
+				[]any{parentID, id},
+			})
+		}
+		statements = append(statements, statement{`
+			DELETE FROM notebooks
+			WHERE id = $1`,
+			[]any{id},
+		})
+
+		for _, st := range statements {
+			if _, err := tx.ExecContext(ctx, st.query, st.args...); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,90 @@
+package postgress
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bojackodin/notes/internal/repository/repositoryerror"
+)
+
+type PasswordResetRepository struct {
+	client *sql.DB
+}
+
+func NewPasswordResetRepository(client *sql.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{
+		client: client,
+	}
+}
+
+// CreateResetToken stores a reset token valid for ttl and invalidates the
+// previously issued tokens of the user. It returns the expiration time.
+func (db *PasswordResetRepository) CreateResetToken(ctx context.Context, userID int64, tokenHash []byte, ttl time.Duration) (time.Time, error) {
+	var expiresAt time.Time
+
+	err := inTx(ctx, db.client, func(tx *sql.Tx) error {
+		query := `
+			UPDATE password_reset_tokens
+			SET used_at = NOW()
+			WHERE user_id = $1 AND used_at IS NULL`
+
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO password_reset_tokens (token_hash, user_id, expires_at)
+			VALUES ($1, $2, NOW() + make_interval(secs => $3))
+			RETURNING expires_at`
+
+		return tx.QueryRowContext(ctx, query, tokenHash, userID, ttl.Seconds()).Scan(&expiresAt)
+	})
+
+	return expiresAt, err
+}
+
+// ResetPassword consumes the reset token, sets the password of its user and
+// revokes all the sessions of the user. It returns
+// repositoryerror.ErrRecordNotFound if the token is unknown, used or expired.
+func (db *PasswordResetRepository) ResetPassword(ctx context.Context, tokenHash, password []byte) (int64, error) {
+	var userID int64
+
+	err := inTx(ctx, db.client, func(tx *sql.Tx) error {
+		query := `
+			UPDATE password_reset_tokens
+			SET used_at = NOW()
+			WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+			RETURNING user_id`
+
+		err := tx.QueryRowContext(ctx, query, tokenHash).Scan(&userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return repositoryerror.ErrRecordNotFound
+			default:
+				return err
+			}
+		}
+
+		query = `
+			UPDATE users
+			SET password = $1
+			WHERE id = $2`
+
+		if _, err = tx.ExecContext(ctx, query, password, userID); err != nil {
+			return err
+		}
+
+		query = `
+			UPDATE sessions
+			SET revoked_at = NOW()
+			WHERE user_id = $1 AND revoked_at IS NULL`
+
+		_, err = tx.ExecContext(ctx, query, userID)
+		return err
+	})
+
+	return userID, err
+}
@@ -0,0 +1,39 @@
+package postgress
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// queryArgs collects positional arguments for a dynamically built query.
+type queryArgs []any
+
+// add appends v and returns its placeholder.
+func (a *queryArgs) add(v any) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern returns an ILIKE pattern matching s anywhere in a value.
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// inTx runs fn in a transaction, committing it if fn returns nil.
+func inTx(ctx context.Context, client *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := client.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,160 @@
+package postgress
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/repository/repositoryerror"
+)
+
+type SessionRepository struct {
+	client *sql.DB
+}
+
+func NewSessionRepository(client *sql.DB) *SessionRepository {
+	return &SessionRepository{
+		client: client,
+	}
+}
+
+// CreateSession stores the session, expiring after ttl, together with its
+// first refresh token.
+func (db *SessionRepository) CreateSession(ctx context.Context, session *entity.Session, tokenHash []byte, ttl time.Duration) error {
+	return inTx(ctx, db.client, func(tx *sql.Tx) error {
+		query := `
+			INSERT INTO sessions (id, user_id, expires_at)
+			VALUES ($1, $2, NOW() + make_interval(secs => $3))
+			RETURNING created_at, expires_at`
+
+		err := tx.QueryRowContext(ctx, query, session.ID, session.UserID, ttl.Seconds()).Scan(
+			&session.CreatedAt,
+			&session.ExpiresAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO refresh_tokens (token_hash, session_id)
+			VALUES ($1, $2)`
+
+		_, err = tx.ExecContext(ctx, query, tokenHash, session.ID)
+		return err
+	})
+}
+
+func (db *SessionRepository) GetSession(ctx context.Context, id string) (entity.Session, error) {
+	query := `
+		SELECT id, user_id, created_at, expires_at, revoked_at
+		FROM sessions
+		WHERE id = $1`
+
+	var session entity.Session
+
+	err := db.client.QueryRowContext(ctx, query, id).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.CreatedAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return entity.Session{}, repositoryerror.ErrRecordNotFound
+		default:
+			return entity.Session{}, err
+		}
+	}
+
+	return session, nil
+}
+
+func (db *SessionRepository) GetRefreshToken(ctx context.Context, tokenHash []byte) (entity.RefreshToken, error) {
+	query := `
+		SELECT token_hash, session_id, created_at, used_at
+		FROM refresh_tokens
+		WHERE token_hash = $1`
+
+	var token entity.RefreshToken
+
+	err := db.client.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.Hash,
+		&token.SessionID,
+		&token.CreatedAt,
+		&token.UsedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return entity.RefreshToken{}, repositoryerror.ErrRecordNotFound
+		default:
+			return entity.RefreshToken{}, err
+		}
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken marks the old refresh token as used, stores the new one
+// and extends the session by ttl. It returns repositoryerror.ErrConflict if
+// the old token has already been used.
+func (db *SessionRepository) RotateRefreshToken(ctx context.Context, session *entity.Session, oldHash, newHash []byte, ttl time.Duration) error {
+	return inTx(ctx, db.client, func(tx *sql.Tx) error {
+		query := `
+			UPDATE refresh_tokens
+			SET used_at = NOW()
+			WHERE token_hash = $1 AND session_id = $2 AND used_at IS NULL`
+
+		result, err := tx.ExecContext(ctx, query, oldHash, session.ID)
+		if err != nil {
+			return err
+		}
+		if err = checkAffected(result); err != nil {
+			if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+				return repositoryerror.ErrConflict
+			}
+			return err
+		}
+
+		query = `
+			INSERT INTO refresh_tokens (token_hash, session_id)
+			VALUES ($1, $2)`
+
+		if _, err = tx.ExecContext(ctx, query, newHash, session.ID); err != nil {
+			return err
+		}
+
+		query = `
+			UPDATE sessions
+			SET expires_at = NOW() + make_interval(secs => $1)
+			WHERE id = $2
+			RETURNING expires_at`
+
+		return tx.QueryRowContext(ctx, query, ttl.Seconds(), session.ID).Scan(&session.ExpiresAt)
+	})
+}
+
+func (db *SessionRepository) RevokeSession(ctx context.Context, id string) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL`
+
+	_, err := db.client.ExecContext(ctx, query, id)
+	return err
+}
+
+// RevokeUserSessions revokes every session of the user except the given one.
+func (db *SessionRepository) RevokeUserSessions(ctx context.Context, userID int64, exceptID string) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL`
+
+	_, err := db.client.ExecContext(ctx, query, userID, exceptID)
+	return err
+}
@@ -0,0 +1,59 @@
+package postgress
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/bojackodin/notes/internal/entity"
+)
+
+type TagRepository struct {
+	client *sql.DB
+}
+
+func NewTagRepository(client *sql.DB) *TagRepository {
+	return &TagRepository{
+		client: client,
+	}
+}
+
+func (db *TagRepository) ListTags(ctx context.Context, userID int64) ([]entity.Tag, error) {
+	query := `
+		SELECT tags.id, tags.user_id, tags.name, count(notes.id)
+		FROM tags
+		LEFT JOIN note_tags ON note_tags.tag_id = tags.id
+		LEFT JOIN notes ON notes.id = note_tags.note_id AND notes.deleted_at IS NULL
+		WHERE tags.user_id = $1
+		GROUP BY tags.id
+		ORDER BY tags.name`
+
+	rows, err := db.client.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]entity.Tag, 0)
+
+	for rows.Next() {
+		var tag entity.Tag
+
+		err := rows.Scan(
+			&tag.ID,
+			&tag.UserID,
+			&tag.Name,
+			&tag.NoteCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,158 @@
+package postgress
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/repository/repositoryerror"
+)
+
+type TwoFactorRepository struct {
+	client *sql.DB
+}
+
+func NewTwoFactorRepository(client *sql.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{
+		client: client,
+	}
+}
+
+func (db *TwoFactorRepository) GetTwoFactor(ctx context.Context, userID int64) (entity.TwoFactor, error) {
+	query := `
+		SELECT user_id, secret, last_step, created_at, confirmed_at
+		FROM two_factor
+		WHERE user_id = $1`
+
+	var tf entity.TwoFactor
+
+	err := db.client.QueryRowContext(ctx, query, userID).Scan(
+		&tf.UserID,
+		&tf.Secret,
+		&tf.LastStep,
+		&tf.CreatedAt,
+		&tf.ConfirmedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return entity.TwoFactor{}, repositoryerror.ErrRecordNotFound
+		default:
+			return entity.TwoFactor{}, err
+		}
+	}
+
+	return tf, nil
+}
+
+// SaveTwoFactor starts an enrollment, replacing a pending one together with
+// its recovery codes. It returns repositoryerror.ErrConflict if the user has
+// a confirmed enrollment.
+func (db *TwoFactorRepository) SaveTwoFactor(ctx context.Context, userID int64, secret string, recoveryCodeHashes [][]byte) error {
+	return inTx(ctx, db.client, func(tx *sql.Tx) error {
+		query := `
+			INSERT INTO two_factor (user_id, secret)
+			VALUES ($1, $2)
+			ON CONFLICT (user_id) DO UPDATE
+			SET secret = EXCLUDED.secret, last_step = 0, created_at = NOW()
+			WHERE two_factor.confirmed_at IS NULL`
+
+		result, err := tx.ExecContext(ctx, query, userID, secret)
+		if err != nil {
+			return err
+		}
+		if err = checkAffected(result); err != nil {
+			if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+				return repositoryerror.ErrConflict
+			}
+			return err
+		}
+
+		query = `
+			DELETE FROM recovery_codes
+			WHERE user_id = $1`
+
+		if _, err = tx.ExecContext(ctx, query, userID); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO recovery_codes (user_id, code_hash)
+			VALUES ($1, $2)`
+
+		for _, hash := range recoveryCodeHashes {
+			if _, err = tx.ExecContext(ctx, query, userID, hash); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ConfirmTwoFactor confirms a pending enrollment with the step of a valid code.
+func (db *TwoFactorRepository) ConfirmTwoFactor(ctx context.Context, userID, step int64) error {
+	query := `
+		UPDATE two_factor
+		SET confirmed_at = NOW(), last_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL`
+
+	result, err := db.client.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+// UseStep records the step of an accepted code. It returns
+// repositoryerror.ErrConflict if a code of the same or a later step has
+// already been accepted.
+func (db *TwoFactorRepository) UseStep(ctx context.Context, userID, step int64) error {
+	query := `
+		UPDATE two_factor
+		SET last_step = $2
+		WHERE user_id = $1 AND last_step < $2`
+
+	result, err := db.client.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+	if err = checkAffected(result); err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return repositoryerror.ErrConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used.
+func (db *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash []byte) error {
+	query := `
+		UPDATE recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	result, err := db.client.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+func (db *TwoFactorRepository) DeleteTwoFactor(ctx context.Context, userID int64) error {
+	query := `
+		DELETE FROM two_factor
+		WHERE user_id = $1`
+
+	result, err := db.client.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
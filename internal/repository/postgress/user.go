@@ -40,7 +40,7 @@ func (db *UserRepository) CreateUser(ctx context.Context, user entity.User) (int
 
 func (db *UserRepository) GetUserByUsername(ctx context.Context, username string) (entity.User, error) {
 	query := `
-		SELECT id, username, password, created_at
+		SELECT id, username, password, role, created_at, disabled_at
         FROM users
         WHERE username = $1
         `
@@ -51,7 +51,9 @@ func (db *UserRepository) GetUserByUsername(ctx context.Context, username string
 		&user.ID,
 		&user.Username,
 		&user.Password,
+		&user.Role,
 		&user.CreatedAt,
+		&user.DisabledAt,
 	)
 	if err != nil {
 		switch {
@@ -66,5 +68,204 @@ func (db *UserRepository) GetUserByUsername(ctx context.Context, username string
 }
 
 func (db *UserRepository) GetUserById(ctx context.Context, id int64) (entity.User, error) {
-	return entity.User{}, nil
+	query := `
+		SELECT id, username, password, role, created_at, disabled_at
+		FROM users
+		WHERE id = $1`
+
+	var user entity.User
+
+	err := db.client.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Password,
+		&user.Role,
+		&user.CreatedAt,
+		&user.DisabledAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return entity.User{}, repositoryerror.ErrRecordNotFound
+		default:
+			return entity.User{}, err
+		}
+	}
+
+	return user, nil
+}
+
+func (db *UserRepository) UpdateUsername(ctx context.Context, id int64, username string) error {
+	query := `
+		UPDATE users
+		SET username = $1
+		WHERE id = $2`
+
+	result, err := db.client.ExecContext(ctx, query, username, id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return repositoryerror.ErrDuplicate
+		}
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+func (db *UserRepository) UpdatePassword(ctx context.Context, id int64, password []byte) error {
+	query := `
+		UPDATE users
+		SET password = $1
+		WHERE id = $2`
+
+	result, err := db.client.ExecContext(ctx, query, password, id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+// ListUsers returns users ordered by ID with their note counts.
+func (db *UserRepository) ListUsers(ctx context.Context, limit, offset int) ([]entity.UserStats, error) {
+	query := `
+		SELECT u.id, u.username, u.role, u.created_at, u.disabled_at, COUNT(n.id)
+		FROM users u
+		LEFT JOIN notes n ON n.user_id = u.id AND n.deleted_at IS NULL
+		GROUP BY u.id
+		ORDER BY u.id
+		LIMIT $1 OFFSET $2`
+
+	rows, err := db.client.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]entity.UserStats, 0)
+
+	for rows.Next() {
+		var user entity.UserStats
+		err = rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Role,
+			&user.CreatedAt,
+			&user.DisabledAt,
+			&user.NoteCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (db *UserRepository) GetUserStats(ctx context.Context, id int64) (entity.UserStats, error) {
+	query := `
+		SELECT u.id, u.username, u.role, u.created_at, u.disabled_at,
+			(SELECT COUNT(*) FROM notes n WHERE n.user_id = u.id AND n.deleted_at IS NULL)
+		FROM users u
+		WHERE u.id = $1`
+
+	var user entity.UserStats
+
+	err := db.client.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Role,
+		&user.CreatedAt,
+		&user.DisabledAt,
+		&user.NoteCount,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return entity.UserStats{}, repositoryerror.ErrRecordNotFound
+		default:
+			return entity.UserStats{}, err
+		}
+	}
+
+	return user, nil
+}
+
+func (db *UserRepository) UpdateRole(ctx context.Context, id int64, role entity.Role) error {
+	query := `
+		UPDATE users
+		SET role = $1
+		WHERE id = $2`
+
+	result, err := db.client.ExecContext(ctx, query, role, id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+// DisableUser disables the account and revokes all its sessions.
+func (db *UserRepository) DisableUser(ctx context.Context, id int64) error {
+	return inTx(ctx, db.client, func(tx *sql.Tx) error {
+		query := `
+			UPDATE users
+			SET disabled_at = COALESCE(disabled_at, NOW())
+			WHERE id = $1`
+
+		result, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+		if err = checkAffected(result); err != nil {
+			return err
+		}
+
+		query = `
+			UPDATE sessions
+			SET revoked_at = NOW()
+			WHERE user_id = $1 AND revoked_at IS NULL`
+
+		_, err = tx.ExecContext(ctx, query, id)
+		return err
+	})
+}
+
+func (db *UserRepository) EnableUser(ctx context.Context, id int64) error {
+	query := `
+		UPDATE users
+		SET disabled_at = NULL
+		WHERE id = $1`
+
+	result, err := db.client.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+// DeleteUser deletes the user together with their notes, tags and notebooks.
+// Sessions and tokens are deleted by cascade.
+func (db *UserRepository) DeleteUser(ctx context.Context, id int64) error {
+	return inTx(ctx, db.client, func(tx *sql.Tx) error {
+		for _, query := range []string{
+			`DELETE FROM notes WHERE user_id = $1`,
+			`DELETE FROM tags WHERE user_id = $1`,
+			`DELETE FROM notebooks WHERE user_id = $1`,
+		} {
+			if _, err := tx.ExecContext(ctx, query, id); err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+		if err != nil {
+			return err
+		}
+
+		return checkAffected(result)
+	})
 }
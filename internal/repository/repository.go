@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/bojackodin/notes/internal/entity"
 	"github.com/bojackodin/notes/internal/repository/postgress"
@@ -12,21 +13,96 @@ type User interface {
 	CreateUser(ctx context.Context, user entity.User) (int64, error)
 	GetUserByUsername(ctx context.Context, username string) (entity.User, error)
 	GetUserById(ctx context.Context, id int64) (entity.User, error)
+	UpdateUsername(ctx context.Context, id int64, username string) error
+	UpdatePassword(ctx context.Context, id int64, password []byte) error
+	ListUsers(ctx context.Context, limit, offset int) ([]entity.UserStats, error)
+	GetUserStats(ctx context.Context, id int64) (entity.UserStats, error)
+	UpdateRole(ctx context.Context, id int64, role entity.Role) error
+	DisableUser(ctx context.Context, id int64) error
+	EnableUser(ctx context.Context, id int64) error
+	DeleteUser(ctx context.Context, id int64) error
 }
 
 type Note interface {
 	CreateNote(ctx context.Context, note *entity.Note) error
-	ListNotes(ctx context.Context, userID int64) ([]entity.Note, error)
+	GetNote(ctx context.Context, id, userID int64) (entity.Note, error)
+	UpdateNote(ctx context.Context, note *entity.Note) error
+	MoveNote(ctx context.Context, note *entity.Note) error
+	DeleteNote(ctx context.Context, id, userID int64, version int) error
+	RestoreNote(ctx context.Context, id, userID int64) error
+	EmptyTrash(ctx context.Context, userID int64) (int64, error)
+	PurgeDeletedNotes(ctx context.Context, retention time.Duration) (int64, error)
+	ListNotes(ctx context.Context, filter entity.NoteFilter) ([]entity.Note, error)
+	SearchNotes(ctx context.Context, search entity.NoteSearch) ([]entity.NoteSearchResult, error)
+	ExportNotes(ctx context.Context, userID int64, fn func(entity.Note) error) error
+	ListRevisions(ctx context.Context, noteID, userID int64) ([]entity.NoteRevision, error)
+	GetRevision(ctx context.Context, noteID, userID int64, revision int) (entity.NoteRevision, error)
+}
+
+type Tag interface {
+	ListTags(ctx context.Context, userID int64) ([]entity.Tag, error)
+}
+
+type Notebook interface {
+	CreateNotebook(ctx context.Context, notebook *entity.Notebook) error
+	GetNotebook(ctx context.Context, id, userID int64) (entity.Notebook, error)
+	ListNotebooks(ctx context.Context, userID int64) ([]entity.Notebook, error)
+	RenameNotebook(ctx context.Context, notebook *entity.Notebook) error
+	MoveNotebook(ctx context.Context, notebook *entity.Notebook) error
+	DeleteNotebook(ctx context.Context, id, userID int64, cascade bool) error
+}
+
+type Session interface {
+	CreateSession(ctx context.Context, session *entity.Session, tokenHash []byte, ttl time.Duration) error
+	GetSession(ctx context.Context, id string) (entity.Session, error)
+	GetRefreshToken(ctx context.Context, tokenHash []byte) (entity.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, session *entity.Session, oldHash, newHash []byte, ttl time.Duration) error
+	RevokeSession(ctx context.Context, id string) error
+	RevokeUserSessions(ctx context.Context, userID int64, exceptID string) error
+}
+
+type AccessToken interface {
+	CreateAccessToken(ctx context.Context, token *entity.AccessToken, tokenHash []byte) error
+	ListAccessTokens(ctx context.Context, userID int64) ([]entity.AccessToken, error)
+	DeleteAccessToken(ctx context.Context, id, userID int64) error
+	UseAccessToken(ctx context.Context, tokenHash []byte) (entity.AccessToken, error)
+}
+
+type TwoFactor interface {
+	GetTwoFactor(ctx context.Context, userID int64) (entity.TwoFactor, error)
+	SaveTwoFactor(ctx context.Context, userID int64, secret string, recoveryCodeHashes [][]byte) error
+	ConfirmTwoFactor(ctx context.Context, userID, step int64) error
+	UseStep(ctx context.Context, userID, step int64) error
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash []byte) error
+	DeleteTwoFactor(ctx context.Context, userID int64) error
+}
+
+type PasswordReset interface {
+	CreateResetToken(ctx context.Context, userID int64, tokenHash []byte, ttl time.Duration) (time.Time, error)
+	ResetPassword(ctx context.Context, tokenHash, password []byte) (int64, error)
 }
 
 type Repositories struct {
 	User
 	Note
+	Tag
+	Notebook
+	Session
+	PasswordReset
+	AccessToken
+	TwoFactor
 }
 
 func NewRepositories(client *sql.DB) *Repositories {
 	return &Repositories{
-		User: postgress.NewUserRepository(client),
-		Note: postgress.NewNoteRepository(client),
+		User:     postgress.NewUserRepository(client),
+		Note:     postgress.NewNoteRepository(client),
+		Tag:      postgress.NewTagRepository(client),
+		Notebook: postgress.NewNotebookRepository(client),
+		Session:  postgress.NewSessionRepository(client),
+
+		PasswordReset: postgress.NewPasswordResetRepository(client),
+		AccessToken:   postgress.NewAccessTokenRepository(client),
+		TwoFactor:     postgress.NewTwoFactorRepository(client),
 	}
 }
@@ -5,4 +5,6 @@ import "errors"
 var (
 	ErrRecordNotFound = errors.New("record not found")
 	ErrDuplicate      = errors.New("duplicate")
+	ErrCycle          = errors.New("cycle")
+	ErrConflict       = errors.New("conflict")
 )
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/repository"
+	"github.com/bojackodin/notes/internal/repository/repositoryerror"
+)
+
+// AccessTokenPrefix tells personal access tokens apart from JWTs.
+const AccessTokenPrefix = "pat_"
+
+const (
+	ScopeNotesRead  = "notes:read"
+	ScopeNotesWrite = "notes:write"
+)
+
+var accessTokenScopes = []string{ScopeNotesRead, ScopeNotesWrite}
+
+const maxAccessTokenNameLength = 255
+
+type CreateAccessTokenInput struct {
+	Name   string
+	Scopes []string
+	// ExpiresAt is nil for a token that never expires.
+	ExpiresAt *time.Time
+}
+
+type AccessTokenService struct {
+	accessTokenRepository repository.AccessToken
+}
+
+func NewAccessTokenService(accessTokenRepository repository.AccessToken) *AccessTokenService {
+	return &AccessTokenService{
+		accessTokenRepository: accessTokenRepository,
+	}
+}
+
+// CreateAccessToken creates a token and returns it together with its secret
+// value. The value is not stored and cannot be retrieved later.
+func (s *AccessTokenService) CreateAccessToken(ctx context.Context, userID int64, input CreateAccessTokenInput) (entity.AccessToken, string, error) {
+	name := strings.TrimSpace(input.Name)
+	scopes := normalizeScopes(input.Scopes)
+
+	if err := validationError(validateAccessToken(name, input.Scopes, scopes, input.ExpiresAt)); err != nil {
+		return entity.AccessToken{}, "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return entity.AccessToken{}, "", err
+	}
+	secret := AccessTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	token := entity.AccessToken{
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		ExpiresAt: input.ExpiresAt,
+	}
+
+	err := s.accessTokenRepository.CreateAccessToken(ctx, &token, hashToken(secret))
+	if err != nil {
+		return entity.AccessToken{}, "", err
+	}
+
+	return token, secret, nil
+}
+
+func (s *AccessTokenService) ListAccessTokens(ctx context.Context, userID int64) ([]entity.AccessToken, error) {
+	return s.accessTokenRepository.ListAccessTokens(ctx, userID)
+}
+
+func (s *AccessTokenService) RevokeAccessToken(ctx context.Context, id, userID int64) error {
+	err := s.accessTokenRepository.DeleteAccessToken(ctx, id, userID)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return ErrAccessTokenNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ParseAccessToken returns the unexpired token with the given value and
+// records its use.
+func (s *AccessTokenService) ParseAccessToken(ctx context.Context, secret string) (entity.AccessToken, error) {
+	if !strings.HasPrefix(secret, AccessTokenPrefix) {
+		return entity.AccessToken{}, ErrInvalidAccessToken
+	}
+
+	token, err := s.accessTokenRepository.UseAccessToken(ctx, hashToken(secret))
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return entity.AccessToken{}, ErrInvalidAccessToken
+		}
+		return entity.AccessToken{}, err
+	}
+
+	return token, nil
+}
+
+func validateAccessToken(name string, rawScopes, scopes []string, expiresAt *time.Time) []FieldError {
+	var errs []FieldError
+
+	switch {
+	case name == "":
+		errs = append(errs, FieldError{Field: "name", Code: CodeRequired, Message: "must not be empty"})
+	case utf8.RuneCountInString(name) > maxAccessTokenNameLength:
+		errs = append(errs, FieldError{Field: "name", Code: CodeTooLong, Message: "must be at most 255 characters"})
+	}
+
+	if len(scopes) == 0 {
+		errs = append(errs, FieldError{Field: "scopes", Code: CodeRequired, Message: "must not be empty"})
+	}
+	for _, scope := range rawScopes {
+		if !slices.Contains(accessTokenScopes, scope) {
+			errs = append(errs, FieldError{Field: "scopes", Code: CodeInvalid, Message: "unknown scope " + scope})
+		}
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		errs = append(errs, FieldError{Field: "expires_at", Code: CodeInvalid, Message: "must be in the future"})
+	}
+
+	return errs
+}
+
+// normalizeScopes returns the known scopes sorted and deduplicated.
+func normalizeScopes(scopes []string) []string {
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if slices.Contains(accessTokenScopes, scope) {
+			result = append(result, scope)
+		}
+	}
+	slices.Sort(result)
+	return slices.Compact(result)
+}
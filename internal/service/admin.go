@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/repository"
+	"github.com/bojackodin/notes/internal/repository/repositoryerror"
+)
+
+const (
+	defaultUsersLimit = 50
+	maxUsersLimit     = 100
+)
+
+type AdminService struct {
+	userRepository    repository.User
+	sessionRepository repository.Session
+}
+
+func NewAdminService(userRepository repository.User, sessionRepository repository.Session) *AdminService {
+	return &AdminService{
+		userRepository:    userRepository,
+		sessionRepository: sessionRepository,
+	}
+}
+
+func (s *AdminService) ListUsers(ctx context.Context, limit, offset int) ([]entity.UserStats, error) {
+	switch {
+	case limit == 0:
+		limit = defaultUsersLimit
+	case limit < 0 || limit > maxUsersLimit:
+		return nil, ErrInvalidLimit
+	}
+	if offset < 0 {
+		return nil, ErrInvalidLimit
+	}
+
+	return s.userRepository.ListUsers(ctx, limit, offset)
+}
+
+func (s *AdminService) GetUser(ctx context.Context, id int64) (entity.UserStats, error) {
+	user, err := s.userRepository.GetUserStats(ctx, id)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return entity.UserStats{}, ErrUserNotFound
+		}
+		return entity.UserStats{}, err
+	}
+
+	return user, nil
+}
+
+// DisableUser disables the account and signs it out everywhere. Personal
+// access tokens of disabled accounts are rejected as well.
+func (s *AdminService) DisableUser(ctx context.Context, actorID, id int64) (entity.UserStats, error) {
+	if actorID == id {
+		return entity.UserStats{}, ErrSelfAdministration
+	}
+
+	if err := s.userRepository.DisableUser(ctx, id); err != nil {
+		return entity.UserStats{}, userError(err)
+	}
+
+	return s.GetUser(ctx, id)
+}
+
+func (s *AdminService) EnableUser(ctx context.Context, id int64) (entity.UserStats, error) {
+	if err := s.userRepository.EnableUser(ctx, id); err != nil {
+		return entity.UserStats{}, userError(err)
+	}
+
+	return s.GetUser(ctx, id)
+}
+
+// SignOutUser revokes every session of the user.
+func (s *AdminService) SignOutUser(ctx context.Context, actorID, id int64) error {
+	if actorID == id {
+		return ErrSelfAdministration
+	}
+
+	if _, err := s.GetUser(ctx, id); err != nil {
+		return err
+	}
+
+	return s.sessionRepository.RevokeUserSessions(ctx, id, "")
+}
+
+// SetRole changes the role of the user and revokes their sessions, so that
+// no access token carries the previous role.
+func (s *AdminService) SetRole(ctx context.Context, actorID, id int64, role entity.Role) (entity.UserStats, error) {
+	if role != entity.RoleUser && role != entity.RoleAdmin {
+		return entity.UserStats{}, ErrInvalidRole
+	}
+	if actorID == id {
+		return entity.UserStats{}, ErrSelfAdministration
+	}
+
+	if err := s.userRepository.UpdateRole(ctx, id, role); err != nil {
+		return entity.UserStats{}, userError(err)
+	}
+
+	if err := s.sessionRepository.RevokeUserSessions(ctx, id, ""); err != nil {
+		return entity.UserStats{}, err
+	}
+
+	return s.GetUser(ctx, id)
+}
+
+func userError(err error) error {
+	if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	return err
+}
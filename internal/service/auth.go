@@ -2,37 +2,154 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/bojackodin/notes/internal/entity"
 	"github.com/bojackodin/notes/internal/repository"
 	"github.com/bojackodin/notes/internal/repository/repositoryerror"
+	"github.com/bojackodin/notes/internal/signing"
+	"github.com/bojackodin/notes/internal/throttle"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/rs/xid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type TokenClaims struct {
 	jwt.StandardClaims
-	UserID int64 `json:"user_id"`
+	UserID int64       `json:"user_id"`
+	Role   entity.Role `json:"role,omitempty"`
+}
+
+// SignInLimits throttles failed sign-in attempts. A nil limiter disables
+// the corresponding check.
+type SignInLimits struct {
+	Username *throttle.Limiter
+	IP       *throttle.Limiter
+}
+
+// attempt reserves a sign-in attempt, counted as failed until success or
+// cancel is called.
+func (l SignInLimits) attempt(usernameKey, clientIP string) error {
+	var (
+		retryAfter       time.Duration
+		usernameOK, ipOK bool
+	)
+
+	if l.Username != nil {
+		var wait time.Duration
+		if wait, usernameOK = l.Username.Attempt(usernameKey); !usernameOK {
+			retryAfter = wait
+		}
+	}
+	if l.IP != nil && clientIP != "" {
+		var wait time.Duration
+		if wait, ipOK = l.IP.Attempt(clientIP); !ipOK {
+			retryAfter = max(retryAfter, wait)
+		}
+	}
+
+	if retryAfter > 0 {
+		// The attempt is rejected, so it is not counted by the other
+		// limiter either.
+		if usernameOK {
+			l.Username.Cancel(usernameKey)
+		}
+		if ipOK {
+			l.IP.Cancel(clientIP)
+		}
+		return &TooManyAttemptsError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// success forgets the failures of the username. Those of the client IP are
+// kept, so that signing in to one account does not reset guessing others.
+func (l SignInLimits) success(usernameKey, clientIP string) {
+	if l.Username != nil {
+		l.Username.Success(usernameKey)
+	}
+	if l.IP != nil && clientIP != "" {
+		l.IP.Cancel(clientIP)
+	}
+}
+
+// cancel takes back an attempt that neither succeeded nor failed, e.g.
+// because of a database error.
+func (l SignInLimits) cancel(usernameKey, clientIP string) {
+	if l.Username != nil {
+		l.Username.Cancel(usernameKey)
+	}
+	if l.IP != nil && clientIP != "" {
+		l.IP.Cancel(clientIP)
+	}
+}
+
+// challengeAudience marks the tokens proving that a user with two-factor
+// authentication has passed the password step of sign-in.
+const challengeAudience = "sign-in-2fa"
+
+// Tokens is the pair issued on sign-in and on refresh. Signing in a user with
+// two-factor authentication only sets ChallengeToken, to be exchanged for
+// the pair by VerifyTwoFactor.
+type Tokens struct {
+	AccessToken    string
+	RefreshToken   string
+	ChallengeToken string
 }
 
 type AuthService struct {
-	userRepository repository.User
-	secret         string
-	tokenTTL       time.Duration
+	userRepository      repository.User
+	sessionRepository   repository.Session
+	twoFactorRepository repository.TwoFactor
+	policy              *Policy
+	limits              SignInLimits
+	keys                *signing.KeySet
+	tokenTTL            time.Duration
+	refreshTokenTTL     time.Duration
+	challengeTTL        time.Duration
 }
 
-func NewAuthService(userRepository repository.User, secret string, tokenTTL time.Duration) *AuthService {
+func NewAuthService(
+	userRepository repository.User,
+	sessionRepository repository.Session,
+	twoFactorRepository repository.TwoFactor,
+	policy *Policy,
+	limits SignInLimits,
+	keys *signing.KeySet,
+	tokenTTL, refreshTokenTTL, challengeTTL time.Duration,
+) *AuthService {
 	return &AuthService{
-		userRepository: userRepository,
-		secret:         secret,
-		tokenTTL:       tokenTTL,
+		userRepository:      userRepository,
+		sessionRepository:   sessionRepository,
+		twoFactorRepository: twoFactorRepository,
+		policy:              policy,
+		limits:              limits,
+		keys:                keys,
+		tokenTTL:            tokenTTL,
+		refreshTokenTTL:     refreshTokenTTL,
+		challengeTTL:        challengeTTL,
 	}
 }
 
 func (s *AuthService) CreateUser(ctx context.Context, username, password string) (int64, error) {
+	username = strings.TrimSpace(username)
+
+	err := validationError(
+		s.policy.ValidateUsername("username", username),
+		s.policy.ValidatePassword("password", password),
+	)
+	if err != nil {
+		return 0, err
+	}
+
 	hash, err := generatePasswordHash(password)
 	if err != nil {
 		return 0, err
@@ -53,53 +170,315 @@ func (s *AuthService) CreateUser(ctx context.Context, username, password string)
 	return userId, nil
 }
 
-func (s *AuthService) GenerateToken(ctx context.Context, username, password string) (string, error) {
+// GenerateToken signs the user in, starting a new session, or returns a
+// challenge token if the user has two-factor authentication enabled. Failed
+// attempts are throttled per username and per client IP.
+func (s *AuthService) GenerateToken(ctx context.Context, username, password, clientIP string) (Tokens, error) {
+	usernameKey := strings.ToLower(username)
+
+	if err := s.limits.attempt(usernameKey, clientIP); err != nil {
+		return Tokens{}, err
+	}
+
 	user, err := s.userRepository.GetUserByUsername(ctx, username)
+	found := err == nil
+	if !found && !errors.Is(err, repositoryerror.ErrRecordNotFound) {
+		s.limits.cancel(usernameKey, clientIP)
+		return Tokens{}, err
+	}
+
+	// Unknown usernames cost the same bcrypt comparison as known ones, so
+	// that response times do not reveal which accounts exist.
+	hash := user.Password
+	if !found {
+		hash = dummyPasswordHash()
+	}
+
+	match, err := matches(hash, password)
+	if err != nil {
+		s.limits.cancel(usernameKey, clientIP)
+		return Tokens{}, err
+	}
+	if !match || !found {
+		return Tokens{}, ErrInvalidCredentials
+	}
+
+	s.limits.success(usernameKey, clientIP)
+
+	if user.DisabledAt != nil {
+		return Tokens{}, ErrUserDisabled
+	}
+
+	enabled, err := s.twoFactorEnabled(ctx, user.ID)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if enabled {
+		challengeToken, err := s.signChallenge(user.ID)
+		if err != nil {
+			return Tokens{}, err
+		}
+		return Tokens{ChallengeToken: challengeToken}, nil
+	}
+
+	return s.startSession(ctx, user)
+}
+
+// VerifyTwoFactor completes the sign-in started by GenerateToken with a TOTP
+// code or a recovery code.
+func (s *AuthService) VerifyTwoFactor(ctx context.Context, challengeToken, code, recoveryCode string) (Tokens, error) {
+	userID, err := s.parseChallenge(challengeToken)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	tf, err := s.twoFactorRepository.GetTwoFactor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return Tokens{}, ErrInvalidChallenge
+		}
+		return Tokens{}, err
+	}
+	if tf.ConfirmedAt == nil {
+		return Tokens{}, ErrInvalidChallenge
+	}
+
+	// Codes are short, so guesses are throttled per user.
+	key := "2fa:" + strconv.FormatInt(userID, 10)
+	if err = s.limits.attempt(key, ""); err != nil {
+		return Tokens{}, err
+	}
+
+	if err = verifySecondFactor(ctx, s.twoFactorRepository, tf, code, recoveryCode); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			return Tokens{}, ErrInvalidCredentials
+		}
+		s.limits.cancel(key, "")
+		return Tokens{}, err
+	}
+
+	s.limits.success(key, "")
+
+	user, err := s.userRepository.GetUserById(ctx, userID)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if user.DisabledAt != nil {
+		return Tokens{}, ErrUserDisabled
+	}
+
+	return s.startSession(ctx, user)
+}
+
+func (s *AuthService) twoFactorEnabled(ctx context.Context, userID int64) (bool, error) {
+	tf, err := s.twoFactorRepository.GetTwoFactor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return tf.ConfirmedAt != nil, nil
+}
+
+func (s *AuthService) startSession(ctx context.Context, user entity.User) (Tokens, error) {
+	refreshToken, refreshHash, err := generateRefreshToken()
 	if err != nil {
-		return "", nil
+		return Tokens{}, err
 	}
 
-	match, err := matches(user.Password, password)
+	session := entity.Session{
+		ID:     xid.New().String(),
+		UserID: user.ID,
+	}
+
+	err = s.sessionRepository.CreateSession(ctx, &session, refreshHash, s.refreshTokenTTL)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	accessToken, err := s.signAccessToken(session, user.Role)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. Every refresh token
+// can be used once; presenting a used one revokes the whole session, as it
+// means the token has leaked.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (Tokens, error) {
+	oldHash := hashToken(refreshToken)
+
+	token, err := s.sessionRepository.GetRefreshToken(ctx, oldHash)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return Tokens{}, ErrInvalidRefreshToken
+		}
+		return Tokens{}, err
+	}
+
+	session, err := s.sessionRepository.GetSession(ctx, token.SessionID)
 	if err != nil {
-		return "", err
+		return Tokens{}, err
+	}
+	if session.RevokedAt != nil || session.ExpiresAt.Before(time.Now()) {
+		return Tokens{}, ErrInvalidRefreshToken
 	}
-	if !match {
-		return "", errors.New("don't match")
+
+	if token.UsedAt != nil {
+		return Tokens{}, s.revokeReusedSession(ctx, session)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &TokenClaims{
+	// The user is read again for the role, which the new access token carries.
+	user, err := s.userRepository.GetUserById(ctx, session.UserID)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if user.DisabledAt != nil {
+		return Tokens{}, ErrUserDisabled
+	}
+
+	newToken, newHash, err := generateRefreshToken()
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	err = s.sessionRepository.RotateRefreshToken(ctx, &session, oldHash, newHash, s.refreshTokenTTL)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrConflict) {
+			return Tokens{}, s.revokeReusedSession(ctx, session)
+		}
+		return Tokens{}, err
+	}
+
+	accessToken, err := s.signAccessToken(session, user.Role)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{AccessToken: accessToken, RefreshToken: newToken}, nil
+}
+
+func (s *AuthService) revokeReusedSession(ctx context.Context, session entity.Session) error {
+	if err := s.sessionRepository.RevokeSession(ctx, session.ID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// SignOut revokes the session, invalidating its access and refresh tokens.
+func (s *AuthService) SignOut(ctx context.Context, sessionID string) error {
+	return s.sessionRepository.RevokeSession(ctx, sessionID)
+}
+
+func (s *AuthService) signAccessToken(session entity.Session, role entity.Role) (string, error) {
+	return s.keys.Sign(&TokenClaims{
 		StandardClaims: jwt.StandardClaims{
+			Id:        session.ID,
 			ExpiresAt: time.Now().Add(s.tokenTTL).Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
-		UserID: user.ID,
+		UserID: session.UserID,
+		Role:   role,
 	})
+}
 
-	return token.SignedString([]byte(s.secret))
+func (s *AuthService) signChallenge(userID int64) (string, error) {
+	return s.keys.Sign(&TokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Audience:  challengeAudience,
+			ExpiresAt: time.Now().Add(s.challengeTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+		UserID: userID,
+	})
 }
 
-func (s *AuthService) ParseToken(accessToken string) (int64, error) {
-	token, err := jwt.ParseWithClaims(accessToken, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
+func (s *AuthService) parseChallenge(challengeToken string) (int64, error) {
+	claims, err := s.parseClaims(challengeToken)
+	if err != nil || !claims.VerifyAudience(challengeAudience, true) {
+		return 0, ErrInvalidChallenge
+	}
+
+	return claims.UserID, nil
+}
+
+// JWKS returns the public keys access tokens can be verified with.
+func (s *AuthService) JWKS() signing.JWKSet {
+	return s.keys.JWKS()
+}
+
+// ParseToken verifies the access token and checks that its session, given by
+// the jti claim, has not been revoked.
+func (s *AuthService) ParseToken(ctx context.Context, accessToken string) (*TokenClaims, error) {
+	claims, err := s.parseClaims(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Audience == challengeAudience {
+		return nil, errors.New("challenge token used as access token")
+	}
+
+	session, err := s.sessionRepository.GetSession(ctx, claims.Id)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return nil, ErrSessionRevoked
 		}
+		return nil, err
+	}
+	if session.RevokedAt != nil || session.UserID != claims.UserID {
+		return nil, ErrSessionRevoked
+	}
 
-		return []byte(s.secret), nil
-	})
+	return claims, nil
+}
+
+func (s *AuthService) parseClaims(tokenString string) (*TokenClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, s.keys.Keyfunc)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	claims, ok := token.Claims.(*TokenClaims)
 	if !ok {
-		return 0, errors.New("token claims are not of type *tokenClaims")
+		return nil, errors.New("token claims are not of type *tokenClaims")
 	}
 
-	return claims.UserID, nil
+	return claims, nil
 }
 
+// generateRefreshToken returns a random token and the hash to store.
+func generateRefreshToken() (string, []byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+const passwordHashCost = 12
+
+// dummyPasswordHash is compared against when signing in an unknown user.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("dummy password"), passwordHashCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
 func generatePasswordHash(password string) ([]byte, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
 	if err != nil {
 		return nil, err
 	}
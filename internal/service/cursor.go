@@ -0,0 +1,56 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/bojackodin/notes/internal/entity"
+)
+
+// noteCursor is the JSON payload of an opaque page cursor. The sort order
+// is embedded so that a cursor cannot be reused with a different one.
+type noteCursor struct {
+	Sort      string    `json:"s"`
+	ID        int64     `json:"id"`
+	Title     string    `json:"t,omitempty"`
+	CreatedAt time.Time `json:"c,omitempty"`
+	UpdatedAt time.Time `json:"u,omitempty"`
+}
+
+func encodeNoteCursor(sort string, field entity.NoteSortField, note entity.Note) string {
+	cursor := noteCursor{Sort: sort, ID: note.ID}
+	switch field {
+	case entity.NoteSortTitle:
+		cursor.Title = note.Title
+	case entity.NoteSortCreated:
+		cursor.CreatedAt = note.CreatedAt
+	case entity.NoteSortUpdated:
+		cursor.UpdatedAt = note.UpdatedAt
+	}
+
+	data, _ := json.Marshal(&cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeNoteCursor(s, sort string) (*entity.NoteCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor noteCursor
+	if err = json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != sort || cursor.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &entity.NoteCursor{
+		ID:        cursor.ID,
+		Title:     cursor.Title,
+		CreatedAt: cursor.CreatedAt,
+		UpdatedAt: cursor.UpdatedAt,
+	}, nil
+}
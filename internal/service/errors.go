@@ -2,6 +2,153 @@ package service
 
 import (
 	"errors"
+	"strings"
+	"time"
+
+	"github.com/bojackodin/notes/internal/speller"
+)
+
+// Kind classifies errors, so that every transport maps them the same way.
+type Kind int
+
+const (
+	// KindInternal is the kind of any error not classified otherwise.
+	KindInternal Kind = iota
+	KindInvalid
+	KindUnauthenticated
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindPreconditionFailed
+	KindUnprocessable
+	KindTooManyRequests
+	// KindUnavailable is the kind of failures of external services.
+	KindUnavailable
 )
 
-var ErrUserDuplicate = errors.New("user duplicate")
+// Error is an error of a known kind.
+type Error struct {
+	kind Kind
+	msg  string
+}
+
+func newError(kind Kind, msg string) *Error {
+	return &Error{kind: kind, msg: msg}
+}
+
+func (e *Error) Error() string { return e.msg }
+
+func (e *Error) Kind() Kind { return e.kind }
+
+// KindOf returns the kind of the first classified error in err's tree.
+func KindOf(err error) Kind {
+	var (
+		kindErr       *Error
+		validationErr *ValidationError
+		spellErr      *SpellError
+		retryErr      *TooManyAttemptsError
+	)
+
+	switch {
+	case errors.As(err, &kindErr):
+		return kindErr.kind
+	case errors.As(err, &validationErr), errors.As(err, &spellErr):
+		return KindUnprocessable
+	case errors.As(err, &retryErr):
+		return KindTooManyRequests
+	case errors.Is(err, speller.ErrUnavailable):
+		return KindUnavailable
+	default:
+		return KindInternal
+	}
+}
+
+var (
+	ErrInvalidCredentials  = newError(KindUnauthenticated, "invalid username or password")
+	ErrUserDuplicate       = newError(KindConflict, "user duplicate")
+	ErrUserNotFound        = newError(KindNotFound, "user not found")
+	ErrUserDisabled        = newError(KindForbidden, "user disabled")
+	ErrInvalidRole         = newError(KindInvalid, "invalid role")
+	ErrSelfAdministration  = newError(KindConflict, "admins cannot disable, sign out or change the role of themselves")
+	ErrInvalidRefreshToken = newError(KindUnauthenticated, "invalid refresh token")
+	ErrRefreshTokenReused  = newError(KindUnauthenticated, "refresh token reused, session revoked")
+	ErrSessionRevoked      = newError(KindUnauthenticated, "session revoked")
+	ErrWrongPassword       = newError(KindForbidden, "wrong password")
+	ErrInvalidResetToken   = newError(KindInvalid, "invalid or expired password reset token")
+	ErrInvalidAccessToken  = newError(KindUnauthenticated, "invalid access token")
+	ErrAccessTokenNotFound = newError(KindNotFound, "access token not found")
+
+	ErrTwoFactorEnabled     = newError(KindConflict, "two-factor authentication already enabled")
+	ErrTwoFactorNotEnrolled = newError(KindNotFound, "two-factor authentication not enrolled")
+	ErrInvalidTwoFactorCode = newError(KindInvalid, "invalid two-factor code")
+	ErrInvalidChallenge     = newError(KindUnauthenticated, "invalid or expired sign-in challenge")
+
+	ErrNoteNotFound     = newError(KindNotFound, "note not found")
+	ErrRevisionNotFound = newError(KindNotFound, "revision not found")
+	ErrDiffTooLarge     = newError(KindUnprocessable, "revisions too large to diff")
+	ErrVersionMismatch  = newError(KindPreconditionFailed, "note version mismatch")
+	ErrInvalidCursor    = newError(KindInvalid, "invalid cursor")
+	ErrInvalidSort      = newError(KindInvalid, "invalid sort")
+	ErrInvalidLimit     = newError(KindInvalid, "invalid limit")
+	ErrEmptyQuery       = newError(KindInvalid, "empty search query")
+	ErrInvalidLang      = newError(KindInvalid, "unsupported search language")
+	ErrInvalidTag       = newError(KindInvalid, "invalid tag")
+
+	ErrInvalidSpellPolicy = newError(KindInvalid, "spell policy must be one of [reject, warn, autocorrect]")
+
+	ErrNotebookNotFound    = newError(KindNotFound, "notebook not found")
+	ErrInvalidNotebookName = newError(KindInvalid, "invalid notebook name")
+	ErrNotebookCycle       = newError(KindConflict, "notebook cannot be moved into itself or its descendant")
+)
+
+// FieldMisspells holds the misspellings found in a single note field.
+type FieldMisspells struct {
+	Field     string
+	Misspells []speller.Misspell
+}
+
+// SpellError reports misspellings per note field.
+type SpellError struct {
+	Fields []FieldMisspells
+}
+
+func (e *SpellError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		err := speller.SpellError{Misspells: f.Misspells}
+		parts = append(parts, f.Field+": "+err.Error())
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// TooManyAttemptsError reports that attempts are throttled after repeated
+// failures.
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return "too many failed attempts, retry in " + e.RetryAfter.Round(time.Second).String()
+}
+
+// FieldError describes why the value of a single input field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError reports the rejected input fields.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.Message)
+	}
+
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bojackodin/notes/internal/entity"
+)
+
+const maxSlugLength = 50
+
+type exportManifest struct {
+	ExportedAt time.Time        `json:"exported_at"`
+	User       exportUser       `json:"user"`
+	Notebooks  []exportNotebook `json:"notebooks"`
+	Notes      []exportNote     `json:"notes"`
+}
+
+type exportUser struct {
+	ID        int64       `json:"id"`
+	Username  string      `json:"username"`
+	Role      entity.Role `json:"role"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type exportNotebook struct {
+	ID        int64     `json:"id"`
+	ParentID  *int64    `json:"parent_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type exportNote struct {
+	ID         int64      `json:"id"`
+	File       string     `json:"file"`
+	Title      string     `json:"title"`
+	NotebookID *int64     `json:"notebook_id"`
+	Tags       []string   `json:"tags"`
+	Version    int        `json:"version"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at"`
+}
+
+// ExportUser writes a ZIP archive of all the user's data to w. Every note,
+// trashed ones under trash/, becomes a Markdown file, and manifest.json
+// holds the metadata of the user, their notebooks and notes. Notes are
+// streamed, so the archive is never held in memory.
+func (s *UserService) ExportUser(ctx context.Context, id int64, w io.Writer) error {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	notebooks, err := s.notebookRepository.ListNotebooks(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	manifest := exportManifest{
+		ExportedAt: time.Now().UTC(),
+		User: exportUser{
+			ID:        user.ID,
+			Username:  user.Username,
+			Role:      user.Role,
+			CreatedAt: user.CreatedAt,
+		},
+		Notebooks: make([]exportNotebook, 0, len(notebooks)),
+		Notes:     make([]exportNote, 0),
+	}
+	for _, notebook := range notebooks {
+		manifest.Notebooks = append(manifest.Notebooks, exportNotebook{
+			ID:        notebook.ID,
+			ParentID:  notebook.ParentID,
+			Name:      notebook.Name,
+			CreatedAt: notebook.CreatedAt,
+			UpdatedAt: notebook.UpdatedAt,
+		})
+	}
+
+	zw := zip.NewWriter(w)
+
+	err = s.noteRepository.ExportNotes(ctx, id, func(note entity.Note) error {
+		dir := "notes"
+		if note.DeletedAt != nil {
+			dir = "trash"
+		}
+		name := fmt.Sprintf("%s/%d-%s.md", dir, note.ID, slug(note.Title))
+
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: note.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(f, noteMarkdown(note)); err != nil {
+			return err
+		}
+
+		tags := note.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		manifest.Notes = append(manifest.Notes, exportNote{
+			ID:         note.ID,
+			File:       name,
+			Title:      note.Title,
+			NotebookID: note.NotebookID,
+			Tags:       tags,
+			Version:    note.Version,
+			CreatedAt:  note.CreatedAt,
+			UpdatedAt:  note.UpdatedAt,
+			DeletedAt:  note.DeletedAt,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     "manifest.json",
+		Method:   zip.Deflate,
+		Modified: manifest.ExportedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(manifest); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func noteMarkdown(note entity.Note) string {
+	var b strings.Builder
+
+	b.WriteString("# ")
+	b.WriteString(note.Title)
+	b.WriteString("\n")
+
+	if note.Body != "" {
+		b.WriteString("\n")
+		b.WriteString(note.Body)
+		if !strings.HasSuffix(note.Body, "\n") {
+			b.WriteString("\n")
+		}
+	}
+
+	return b.String()
+}
+
+// slug turns the title into a file name part, keeping letters and digits of
+// any script.
+func slug(title string) string {
+	var (
+		b      strings.Builder
+		n      int
+		dashed = true
+	)
+
+	for _, r := range strings.ToLower(title) {
+		if n == maxSlugLength {
+			break
+		}
+
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			dashed = false
+		case !dashed:
+			b.WriteRune('-')
+			dashed = true
+		default:
+			continue
+		}
+		n++
+	}
+
+	s := strings.TrimSuffix(b.String(), "-")
+	if s == "" {
+		return "untitled"
+	}
+
+	return s
+}
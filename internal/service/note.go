@@ -2,43 +2,562 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/bojackodin/notes/internal/diff"
 	"github.com/bojackodin/notes/internal/entity"
 	"github.com/bojackodin/notes/internal/repository"
-	"github.com/bojackodin/notes/internal/yandex/speller"
+	"github.com/bojackodin/notes/internal/repository/repositoryerror"
+	"github.com/bojackodin/notes/internal/speller"
+)
+
+const (
+	maxTitleLength = 200
+	// maxBodyLength bounds the cost of spell checking, diffing and
+	// exporting a note.
+	maxBodyLength = 100_000
 )
 
 type NoteService struct {
-	noteRepository repository.Note
-	speller        speller.Speller
+	noteRepository     repository.Note
+	notebookRepository repository.Notebook
+	speller            speller.Speller
+	defaultSpellPolicy SpellPolicy
 }
 
-func NewNoteService(noteRepository repository.Note, speller speller.Speller) *NoteService {
+func NewNoteService(noteRepository repository.Note, notebookRepository repository.Notebook, speller speller.Speller, spellPolicy SpellPolicy) *NoteService {
 	return &NoteService{
-		noteRepository: noteRepository,
-		speller:        speller,
+		noteRepository:     noteRepository,
+		notebookRepository: notebookRepository,
+		speller:            speller,
+		defaultSpellPolicy: spellPolicy,
 	}
 }
 
-func (s *NoteService) CreateNote(ctx context.Context, title string, userID int64) (int64, error) {
-	err := s.speller.Check(ctx, title)
+type CreateNoteInput struct {
+	Title      string
+	Body       string
+	Tags       []string
+	NotebookID *int64
+	// SpellPolicy overrides the default spell policy if set.
+	SpellPolicy string
+}
+
+func (s *NoteService) CreateNote(ctx context.Context, userID int64, input CreateNoteInput) (entity.Note, SpellCheck, error) {
+	policy, err := s.spellPolicy(input.SpellPolicy)
+	if err != nil {
+		return entity.Note{}, SpellCheck{}, err
+	}
+
+	if err = validateNote(&input.Title, &input.Body); err != nil {
+		return entity.Note{}, SpellCheck{}, err
+	}
+
+	tags, err := normalizeTags(input.Tags)
+	if err != nil {
+		return entity.Note{}, SpellCheck{}, err
+	}
+
+	if err = s.checkNotebook(ctx, input.NotebookID, userID); err != nil {
+		return entity.Note{}, SpellCheck{}, err
+	}
+
+	fields := map[string]string{
+		"title": input.Title,
+		"body":  input.Body,
+	}
+	check, err := s.applySpellPolicy(ctx, policy, fields)
 	if err != nil {
-		return 0, err
+		return entity.Note{}, SpellCheck{}, err
 	}
 
 	note := entity.Note{
-		Title:  title,
-		UserID: userID,
+		Title:      fields["title"],
+		Body:       fields["body"],
+		Tags:       tags,
+		NotebookID: input.NotebookID,
+		UserID:     userID,
 	}
 
 	err = s.noteRepository.CreateNote(ctx, &note)
 	if err != nil {
-		return 0, err
+		return entity.Note{}, SpellCheck{}, err
+	}
+
+	return note, check, nil
+}
+
+func (s *NoteService) GetNote(ctx context.Context, id, userID int64) (entity.Note, error) {
+	note, err := s.noteRepository.GetNote(ctx, id, userID)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return entity.Note{}, ErrNoteNotFound
+		}
+		return entity.Note{}, err
+	}
+
+	return note, nil
+}
+
+// UpdateNoteInput holds the note fields to change. Nil fields are left as is.
+type UpdateNoteInput struct {
+	Title *string
+	Body  *string
+	Tags  *[]string
+	// Version is the version the client expects the note to have,
+	// zero to skip the check.
+	Version int
+	// SpellPolicy overrides the default spell policy if set.
+	SpellPolicy string
+}
+
+// UpdateNote checks the spelling of the changed fields only.
+func (s *NoteService) UpdateNote(ctx context.Context, id, userID int64, input UpdateNoteInput) (entity.Note, SpellCheck, error) {
+	policy, err := s.spellPolicy(input.SpellPolicy)
+	if err != nil {
+		return entity.Note{}, SpellCheck{}, err
+	}
+
+	if err = validateNote(input.Title, input.Body); err != nil {
+		return entity.Note{}, SpellCheck{}, err
+	}
+
+	note, err := s.getNoteVersion(ctx, id, userID, input.Version)
+	if err != nil {
+		return entity.Note{}, SpellCheck{}, err
+	}
+
+	changed := make(map[string]string)
+	if input.Title != nil && *input.Title != note.Title {
+		changed["title"] = *input.Title
+	}
+	if input.Body != nil && *input.Body != note.Body {
+		changed["body"] = *input.Body
+	}
+
+	if input.Tags != nil {
+		note.Tags, err = normalizeTags(*input.Tags)
+		if err != nil {
+			return entity.Note{}, SpellCheck{}, err
+		}
+	}
+
+	check, err := s.applySpellPolicy(ctx, policy, changed)
+	if err != nil {
+		return entity.Note{}, SpellCheck{}, err
+	}
+	if title, ok := changed["title"]; ok {
+		note.Title = title
+	}
+	if body, ok := changed["body"]; ok {
+		note.Body = body
+	}
+
+	err = s.noteRepository.UpdateNote(ctx, &note)
+	if err != nil {
+		return entity.Note{}, SpellCheck{}, noteWriteError(err)
+	}
+
+	return note, check, nil
+}
+
+// MoveNote moves the note into the notebook, or out of any notebook when
+// notebookID is nil. A non-zero version must match the current one.
+func (s *NoteService) MoveNote(ctx context.Context, id, userID int64, notebookID *int64, version int) (entity.Note, error) {
+	note, err := s.getNoteVersion(ctx, id, userID, version)
+	if err != nil {
+		return entity.Note{}, err
+	}
+
+	if err = s.checkNotebook(ctx, notebookID, userID); err != nil {
+		return entity.Note{}, err
+	}
+	note.NotebookID = notebookID
+
+	err = s.noteRepository.MoveNote(ctx, &note)
+	if err != nil {
+		return entity.Note{}, noteWriteError(err)
+	}
+
+	return note, nil
+}
+
+// DeleteNote moves the note to the trash. A non-zero version must match
+// the current one.
+func (s *NoteService) DeleteNote(ctx context.Context, id, userID int64, version int) error {
+	note, err := s.getNoteVersion(ctx, id, userID, version)
+	if err != nil {
+		return err
+	}
+
+	err = s.noteRepository.DeleteNote(ctx, id, userID, note.Version)
+	if err != nil {
+		return noteWriteError(err)
+	}
+
+	return nil
+}
+
+// validateNote checks the lengths, in characters, of the title and the body.
+// Nil fields are not checked.
+func validateNote(title, body *string) error {
+	var errs []FieldError
+	if title != nil && utf8.RuneCountInString(*title) > maxTitleLength {
+		errs = append(errs, FieldError{Field: "title", Code: CodeTooLong, Message: "must be at most " + strconv.Itoa(maxTitleLength) + " characters"})
+	}
+	if body != nil && utf8.RuneCountInString(*body) > maxBodyLength {
+		errs = append(errs, FieldError{Field: "body", Code: CodeTooLong, Message: "must be at most " + strconv.Itoa(maxBodyLength) + " characters"})
+	}
+
+	return validationError(errs)
+}
+
+// getNoteVersion returns the note, or ErrVersionMismatch if version is not
+// zero and differs from the current version of the note.
+func (s *NoteService) getNoteVersion(ctx context.Context, id, userID int64, version int) (entity.Note, error) {
+	note, err := s.GetNote(ctx, id, userID)
+	if err != nil {
+		return entity.Note{}, err
+	}
+
+	if version != 0 && note.Version != version {
+		return entity.Note{}, ErrVersionMismatch
+	}
+
+	return note, nil
+}
+
+// noteWriteError maps the repository errors of a conditional note write.
+func noteWriteError(err error) error {
+	switch {
+	case errors.Is(err, repositoryerror.ErrRecordNotFound):
+		return ErrNoteNotFound
+	case errors.Is(err, repositoryerror.ErrConflict):
+		return ErrVersionMismatch
+	default:
+		return err
+	}
+}
+
+// RestoreNote moves the note out of the trash.
+func (s *NoteService) RestoreNote(ctx context.Context, id, userID int64) (entity.Note, error) {
+	err := s.noteRepository.RestoreNote(ctx, id, userID)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return entity.Note{}, ErrNoteNotFound
+		}
+		return entity.Note{}, err
+	}
+
+	return s.GetNote(ctx, id, userID)
+}
+
+// EmptyTrash permanently deletes the trashed notes and returns their number.
+func (s *NoteService) EmptyTrash(ctx context.Context, userID int64) (int64, error) {
+	return s.noteRepository.EmptyTrash(ctx, userID)
+}
+
+func (s *NoteService) ListRevisions(ctx context.Context, noteID, userID int64) ([]entity.NoteRevision, error) {
+	if _, err := s.GetNote(ctx, noteID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.noteRepository.ListRevisions(ctx, noteID, userID)
+}
+
+func (s *NoteService) GetRevision(ctx context.Context, noteID, userID int64, revision int) (entity.NoteRevision, error) {
+	rev, err := s.noteRepository.GetRevision(ctx, noteID, userID, revision)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			if _, err = s.GetNote(ctx, noteID, userID); err != nil {
+				return entity.NoteRevision{}, err
+			}
+			return entity.NoteRevision{}, ErrRevisionNotFound
+		}
+		return entity.NoteRevision{}, err
+	}
+
+	return rev, nil
+}
+
+// DiffRevisions returns a unified diff of the title, tags and body between
+// two revisions of the note.
+func (s *NoteService) DiffRevisions(ctx context.Context, noteID, userID int64, from, to int) (string, error) {
+	fromRev, err := s.GetRevision(ctx, noteID, userID, from)
+	if err != nil {
+		return "", err
+	}
+	toRev, err := s.GetRevision(ctx, noteID, userID, to)
+	if err != nil {
+		return "", err
+	}
+
+	fields := []struct {
+		name     string
+		from, to string
+	}{
+		{"title", fromRev.Title + "\n", toRev.Title + "\n"},
+		{"tags", tagLines(fromRev.Tags), tagLines(toRev.Tags)},
+		{"body", fromRev.Body, toRev.Body},
+	}
+
+	var sb strings.Builder
+	for _, f := range fields {
+		d, err := diff.Unified(
+			fmt.Sprintf("a/%s@%d", f.name, from),
+			fmt.Sprintf("b/%s@%d", f.name, to),
+			f.from, f.to, 3,
+		)
+		if err != nil {
+			if errors.Is(err, diff.ErrTooLarge) {
+				return "", ErrDiffTooLarge
+			}
+			return "", err
+		}
+		sb.WriteString(d)
+	}
+
+	return sb.String(), nil
+}
+
+func tagLines(tags []string) string {
+	var sb strings.Builder
+	for _, tag := range tags {
+		sb.WriteString(tag + "\n")
+	}
+	return sb.String()
+}
+
+// RestoreRevision overwrites the note with the content of the revision,
+// which is recorded as a new revision. A non-zero version must match the
+// current one.
+func (s *NoteService) RestoreRevision(ctx context.Context, noteID, userID int64, revision, version int) (entity.Note, error) {
+	rev, err := s.GetRevision(ctx, noteID, userID, revision)
+	if err != nil {
+		return entity.Note{}, err
+	}
+
+	note, err := s.getNoteVersion(ctx, noteID, userID, version)
+	if err != nil {
+		return entity.Note{}, err
+	}
+	note.Title = rev.Title
+	note.Body = rev.Body
+	note.Tags = rev.Tags
+
+	err = s.noteRepository.UpdateNote(ctx, &note)
+	if err != nil {
+		return entity.Note{}, noteWriteError(err)
+	}
+
+	return note, nil
+}
+
+const (
+	defaultNotesLimit = 20
+	maxNotesLimit     = 100
+)
+
+// ListNotesParams describes a page request. Sort is one of the
+// entity.NoteSortField values, optionally prefixed with "-" for descending
+// order. Cursor is the NextCursor of the previous page.
+type ListNotesParams struct {
+	NotebookID *int64
+	// Deleted lists the trash instead of live notes.
+	Deleted bool
+
+	Limit  int
+	Cursor string
+	Sort   string
+
+	Title         string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Tags          []string
+	TagsMatchAny  bool
+}
+
+type NotePage struct {
+	Notes []entity.Note
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
+
+func (s *NoteService) ListNotes(ctx context.Context, userID int64, params ListNotesParams) (NotePage, error) {
+	limit := params.Limit
+	switch {
+	case limit == 0:
+		limit = defaultNotesLimit
+	case limit < 0 || limit > maxNotesLimit:
+		return NotePage{}, ErrInvalidLimit
+	}
+
+	sort := params.Sort
+	if sort == "" {
+		sort = string(entity.NoteSortID)
+	}
+	field, desc := entity.NoteSortField(strings.TrimPrefix(sort, "-")), strings.HasPrefix(sort, "-")
+	switch field {
+	case entity.NoteSortID, entity.NoteSortCreated, entity.NoteSortUpdated, entity.NoteSortTitle:
+	default:
+		return NotePage{}, ErrInvalidSort
+	}
+
+	tags, err := normalizeTags(params.Tags)
+	if err != nil {
+		return NotePage{}, err
+	}
+
+	if err = s.checkNotebook(ctx, params.NotebookID, userID); err != nil {
+		return NotePage{}, err
+	}
+
+	filter := entity.NoteFilter{
+		UserID:        userID,
+		Deleted:       params.Deleted,
+		NotebookID:    params.NotebookID,
+		Title:         params.Title,
+		CreatedAfter:  params.CreatedAfter,
+		CreatedBefore: params.CreatedBefore,
+		Tags:          tags,
+		TagsMatchAny:  params.TagsMatchAny,
+		Sort:          field,
+		Desc:          desc,
+		Limit:         limit + 1,
+	}
+
+	if params.Cursor != "" {
+		after, err := decodeNoteCursor(params.Cursor, sort)
+		if err != nil {
+			return NotePage{}, err
+		}
+		filter.After = after
+	}
+
+	notes, err := s.noteRepository.ListNotes(ctx, filter)
+	if err != nil {
+		return NotePage{}, err
+	}
+
+	var page NotePage
+	if len(notes) > limit {
+		notes = notes[:limit]
+		page.NextCursor = encodeNoteCursor(sort, field, notes[limit-1])
+	}
+	page.Notes = notes
+
+	return page, nil
+}
+
+// SearchNotesParams describes a full-text query. Lang is "en", "ru" or empty,
+// in which case the language is guessed from the query script.
+type SearchNotesParams struct {
+	Query  string
+	Lang   string
+	Limit  int
+	Offset int
+}
+
+func (s *NoteService) SearchNotes(ctx context.Context, userID int64, params SearchNotesParams) ([]entity.NoteSearchResult, error) {
+	query := strings.TrimSpace(params.Query)
+	if query == "" {
+		return nil, ErrEmptyQuery
+	}
+
+	limit := params.Limit
+	switch {
+	case limit == 0:
+		limit = defaultNotesLimit
+	case limit < 0 || limit > maxNotesLimit:
+		return nil, ErrInvalidLimit
+	}
+	if params.Offset < 0 {
+		return nil, ErrInvalidLimit
+	}
+
+	var lang entity.SearchLanguage
+	switch params.Lang {
+	case "en":
+		lang = entity.SearchLanguageEnglish
+	case "ru":
+		lang = entity.SearchLanguageRussian
+	case "":
+		lang = detectLanguage(query)
+	default:
+		return nil, ErrInvalidLang
+	}
+
+	return s.noteRepository.SearchNotes(ctx, entity.NoteSearch{
+		UserID:   userID,
+		Query:    query,
+		Language: lang,
+		Limit:    limit,
+		Offset:   params.Offset,
+	})
+}
+
+// detectLanguage picks Russian stemming when the text contains Cyrillic letters.
+func detectLanguage(text string) entity.SearchLanguage {
+	for _, r := range text {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return entity.SearchLanguageRussian
+		}
+	}
+	return entity.SearchLanguageEnglish
+}
+
+// checkNotebook verifies that the notebook, if any, belongs to the user.
+func (s *NoteService) checkNotebook(ctx context.Context, notebookID *int64, userID int64) error {
+	if notebookID == nil {
+		return nil
+	}
+
+	_, err := s.notebookRepository.GetNotebook(ctx, *notebookID, userID)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return ErrNotebookNotFound
+		}
+		return err
 	}
 
-	return note.ID, nil
+	return nil
 }
 
-func (s *NoteService) ListNotes(ctx context.Context, userID int64) ([]entity.Note, error) {
-	return s.noteRepository.ListNotes(ctx, userID)
+var noteFields = []string{"title", "body"}
+
+// checkSpelling runs the speller over every non-empty field and collects
+// misspellings into a single *SpellError.
+func (s *NoteService) checkSpelling(ctx context.Context, fields map[string]string) error {
+	var spellErr SpellError
+
+	for _, field := range noteFields {
+		text, ok := fields[field]
+		if !ok || text == "" {
+			continue
+		}
+
+		err := s.speller.Check(ctx, text)
+		if err != nil {
+			var fieldErr *speller.SpellError
+			if !errors.As(err, &fieldErr) {
+				return err
+			}
+			spellErr.Fields = append(spellErr.Fields, FieldMisspells{
+				Field:     field,
+				Misspells: fieldErr.Misspells,
+			})
+		}
+	}
+
+	if len(spellErr.Fields) > 0 {
+		return &spellErr
+	}
+
+	return nil
 }
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/repository"
+	"github.com/bojackodin/notes/internal/repository/repositoryerror"
+)
+
+const maxNotebookNameLength = 255
+
+type NotebookService struct {
+	notebookRepository repository.Notebook
+}
+
+func NewNotebookService(notebookRepository repository.Notebook) *NotebookService {
+	return &NotebookService{
+		notebookRepository: notebookRepository,
+	}
+}
+
+func (s *NotebookService) CreateNotebook(ctx context.Context, userID int64, name string, parentID *int64) (entity.Notebook, error) {
+	name, err := normalizeNotebookName(name)
+	if err != nil {
+		return entity.Notebook{}, err
+	}
+
+	if parentID != nil {
+		if _, err = s.GetNotebook(ctx, *parentID, userID); err != nil {
+			return entity.Notebook{}, err
+		}
+	}
+
+	notebook := entity.Notebook{
+		UserID:   userID,
+		ParentID: parentID,
+		Name:     name,
+	}
+
+	if err = s.notebookRepository.CreateNotebook(ctx, &notebook); err != nil {
+		return entity.Notebook{}, err
+	}
+
+	return notebook, nil
+}
+
+func (s *NotebookService) GetNotebook(ctx context.Context, id, userID int64) (entity.Notebook, error) {
+	notebook, err := s.notebookRepository.GetNotebook(ctx, id, userID)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return entity.Notebook{}, ErrNotebookNotFound
+		}
+		return entity.Notebook{}, err
+	}
+
+	return notebook, nil
+}
+
+func (s *NotebookService) ListNotebooks(ctx context.Context, userID int64) ([]entity.Notebook, error) {
+	return s.notebookRepository.ListNotebooks(ctx, userID)
+}
+
+func (s *NotebookService) RenameNotebook(ctx context.Context, id, userID int64, name string) (entity.Notebook, error) {
+	name, err := normalizeNotebookName(name)
+	if err != nil {
+		return entity.Notebook{}, err
+	}
+
+	notebook, err := s.GetNotebook(ctx, id, userID)
+	if err != nil {
+		return entity.Notebook{}, err
+	}
+	notebook.Name = name
+
+	if err = s.notebookRepository.RenameNotebook(ctx, &notebook); err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return entity.Notebook{}, ErrNotebookNotFound
+		}
+		return entity.Notebook{}, err
+	}
+
+	return notebook, nil
+}
+
+// MoveNotebook makes the notebook a child of parentID, or a top-level
+// notebook when parentID is nil.
+func (s *NotebookService) MoveNotebook(ctx context.Context, id, userID int64, parentID *int64) (entity.Notebook, error) {
+	notebook, err := s.GetNotebook(ctx, id, userID)
+	if err != nil {
+		return entity.Notebook{}, err
+	}
+
+	if parentID != nil {
+		if _, err = s.GetNotebook(ctx, *parentID, userID); err != nil {
+			return entity.Notebook{}, err
+		}
+	}
+	notebook.ParentID = parentID
+
+	if err = s.notebookRepository.MoveNotebook(ctx, &notebook); err != nil {
+		switch {
+		case errors.Is(err, repositoryerror.ErrRecordNotFound):
+			return entity.Notebook{}, ErrNotebookNotFound
+		case errors.Is(err, repositoryerror.ErrCycle):
+			return entity.Notebook{}, ErrNotebookCycle
+		default:
+			return entity.Notebook{}, err
+		}
+	}
+
+	return notebook, nil
+}
+
+// DeleteNotebook deletes the notebook. Unless cascade is set, its notes and
+// child notebooks are kept and moved to the parent notebook.
+func (s *NotebookService) DeleteNotebook(ctx context.Context, id, userID int64, cascade bool) error {
+	err := s.notebookRepository.DeleteNotebook(ctx, id, userID, cascade)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return ErrNotebookNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func normalizeNotebookName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNotebookNameLength {
+		return "", ErrInvalidNotebookName
+	}
+	return name, nil
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bojackodin/notes/internal/log"
+	"github.com/bojackodin/notes/internal/notifier"
+	"github.com/bojackodin/notes/internal/repository"
+	"github.com/bojackodin/notes/internal/repository/repositoryerror"
+)
+
+type PasswordService struct {
+	userRepository          repository.User
+	sessionRepository       repository.Session
+	passwordResetRepository repository.PasswordReset
+	notifier                notifier.Notifier
+	policy                  *Policy
+	resetLimits             SignInLimits
+	resetTokenTTL           time.Duration
+}
+
+func NewPasswordService(
+	userRepository repository.User,
+	sessionRepository repository.Session,
+	passwordResetRepository repository.PasswordReset,
+	notifier notifier.Notifier,
+	policy *Policy,
+	resetLimits SignInLimits,
+	resetTokenTTL time.Duration,
+) *PasswordService {
+	return &PasswordService{
+		userRepository:          userRepository,
+		sessionRepository:       sessionRepository,
+		passwordResetRepository: passwordResetRepository,
+		notifier:                notifier,
+		policy:                  policy,
+		resetLimits:             resetLimits,
+		resetTokenTTL:           resetTokenTTL,
+	}
+}
+
+// ChangePassword sets a new password after checking the old one and revokes
+// every session of the user except the current one.
+func (s *PasswordService) ChangePassword(ctx context.Context, userID int64, sessionID, oldPassword, newPassword string) error {
+	if err := validationError(s.policy.ValidatePassword("new_password", newPassword)); err != nil {
+		return err
+	}
+
+	user, err := s.userRepository.GetUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	match, err := matches(user.Password, oldPassword)
+	if err != nil {
+		return err
+	}
+	if !match {
+		return ErrWrongPassword
+	}
+
+	hash, err := generatePasswordHash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	if err = s.userRepository.UpdatePassword(ctx, userID, hash); err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	return s.sessionRepository.RevokeUserSessions(ctx, userID, sessionID)
+}
+
+// RequestPasswordReset issues a reset token and sends it to the user in the
+// background. Requests are throttled per username and per client IP, each one
+// counting as an attempt. Whether the username exists is not reported, and
+// the response does not wait for the lookup, the token or the delivery, so
+// that neither errors nor response times reveal which accounts exist.
+func (s *PasswordService) RequestPasswordReset(ctx context.Context, username, clientIP string) error {
+	if err := s.resetLimits.attempt(strings.ToLower(username), clientIP); err != nil {
+		return err
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := s.sendResetToken(ctx, username); err != nil {
+			log.FromContext(ctx).Error("failed to send password reset token", log.Err(err))
+		}
+	}()
+
+	return nil
+}
+
+func (s *PasswordService) sendResetToken(ctx context.Context, username string) error {
+	user, err := s.userRepository.GetUserByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			log.FromContext(ctx).Info("password reset requested for unknown user")
+			return nil
+		}
+		return err
+	}
+	if user.DisabledAt != nil {
+		log.FromContext(ctx).Info("password reset requested for disabled user")
+		return nil
+	}
+
+	token, hash, err := generateRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	expiresAt, err := s.passwordResetRepository.CreateResetToken(ctx, user.ID, hash, s.resetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.Notify(ctx, notifier.Message{
+		UserID:   user.ID,
+		Username: user.Username,
+		Subject:  "Password reset",
+		Body: fmt.Sprintf("Use the token %s to reset your password. It expires at %s.",
+			token, expiresAt.UTC().Format(time.RFC3339)),
+	})
+}
+
+// ResetPassword sets a new password using a reset token and revokes every
+// session of the user. The token can only be used once.
+func (s *PasswordService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if err := validationError(s.policy.ValidatePassword("new_password", newPassword)); err != nil {
+		return err
+	}
+
+	hash, err := generatePasswordHash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.passwordResetRepository.ResetPassword(ctx, hashToken(token), hash)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	return nil
+}
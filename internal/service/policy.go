@@ -0,0 +1,178 @@
+package service
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bcryptMaxBytes is the longest password bcrypt hashes without truncation.
+const bcryptMaxBytes = 72
+
+// Validation error codes.
+const (
+	CodeRequired     = "required"
+	CodeInvalid      = "invalid"
+	CodeTooShort     = "too_short"
+	CodeTooLong      = "too_long"
+	CodeInvalidChars = "invalid_characters"
+	CodeReserved     = "reserved"
+	CodeMissingClass = "missing_character_class"
+	CodeCommon       = "too_common"
+)
+
+type UsernamePolicy struct {
+	MinLength int
+	MaxLength int
+	// Pattern, if set, must match the username.
+	Pattern *regexp.Regexp
+	// Reserved names are compared case-insensitively.
+	Reserved []string
+}
+
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// Denylist holds lowercased common passwords.
+	Denylist map[string]struct{}
+}
+
+// Policy defines which usernames and passwords are accepted.
+type Policy struct {
+	Username UsernamePolicy
+	Password PasswordPolicy
+}
+
+// ValidateUsername returns the violations of the username policy.
+func (p *Policy) ValidateUsername(field, username string) []FieldError {
+	up := p.Username
+
+	if username == "" {
+		return []FieldError{{Field: field, Code: CodeRequired, Message: "must not be empty"}}
+	}
+
+	var errs []FieldError
+
+	length := utf8.RuneCountInString(username)
+	if length < up.MinLength {
+		errs = append(errs, FieldError{Field: field, Code: CodeTooShort, Message: "must be at least " + strconv.Itoa(up.MinLength) + " characters"})
+	}
+	if up.MaxLength > 0 && length > up.MaxLength {
+		errs = append(errs, FieldError{Field: field, Code: CodeTooLong, Message: "must be at most " + strconv.Itoa(up.MaxLength) + " characters"})
+	}
+	if up.Pattern != nil && !up.Pattern.MatchString(username) {
+		errs = append(errs, FieldError{Field: field, Code: CodeInvalidChars, Message: "must match " + up.Pattern.String()})
+	}
+	for _, name := range up.Reserved {
+		if strings.EqualFold(username, name) {
+			errs = append(errs, FieldError{Field: field, Code: CodeReserved, Message: "is reserved"})
+			break
+		}
+	}
+
+	return errs
+}
+
+// ValidatePassword returns the violations of the password policy. Passwords
+// longer than bcrypt accepts are always rejected.
+func (p *Policy) ValidatePassword(field, password string) []FieldError {
+	pp := p.Password
+
+	if password == "" {
+		return []FieldError{{Field: field, Code: CodeRequired, Message: "must not be empty"}}
+	}
+
+	var errs []FieldError
+
+	length := utf8.RuneCountInString(password)
+	if length < pp.MinLength {
+		errs = append(errs, FieldError{Field: field, Code: CodeTooShort, Message: "must be at least " + strconv.Itoa(pp.MinLength) + " characters"})
+	}
+	if pp.MaxLength > 0 && length > pp.MaxLength {
+		errs = append(errs, FieldError{Field: field, Code: CodeTooLong, Message: "must be at most " + strconv.Itoa(pp.MaxLength) + " characters"})
+	} else if len(password) > bcryptMaxBytes {
+		errs = append(errs, FieldError{Field: field, Code: CodeTooLong, Message: "must be at most " + strconv.Itoa(bcryptMaxBytes) + " bytes"})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r), unicode.IsSymbol(r), unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	var missing []string
+	if pp.RequireUpper && !hasUpper {
+		missing = append(missing, "an uppercase letter")
+	}
+	if pp.RequireLower && !hasLower {
+		missing = append(missing, "a lowercase letter")
+	}
+	if pp.RequireDigit && !hasDigit {
+		missing = append(missing, "a digit")
+	}
+	if pp.RequireSymbol && !hasSymbol {
+		missing = append(missing, "a symbol")
+	}
+	if len(missing) > 0 {
+		errs = append(errs, FieldError{Field: field, Code: CodeMissingClass, Message: "must contain " + strings.Join(missing, ", ")})
+	}
+
+	if _, ok := pp.Denylist[strings.ToLower(password)]; ok {
+		errs = append(errs, FieldError{Field: field, Code: CodeCommon, Message: "is too common"})
+	}
+
+	return errs
+}
+
+func validationError(errs ...[]FieldError) error {
+	var all []FieldError
+	for _, e := range errs {
+		all = append(all, e...)
+	}
+	if len(all) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: all}
+}
+
+// LoadDenylist reads common passwords from a file, one per line. Empty lines
+// and lines starting with # are skipped.
+func LoadDenylist(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	denylist := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		denylist[strings.ToLower(line)] = struct{}{}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return denylist, nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bojackodin/notes/internal/log"
+	"github.com/bojackodin/notes/internal/repository"
+)
+
+// TrashPurger periodically removes notes that have been in the trash for
+// longer than the retention period.
+type TrashPurger struct {
+	noteRepository repository.Note
+	retention      time.Duration
+	interval       time.Duration
+	logger         *slog.Logger
+}
+
+func NewTrashPurger(noteRepository repository.Note, retention, interval time.Duration, logger *slog.Logger) *TrashPurger {
+	return &TrashPurger{
+		noteRepository: noteRepository,
+		retention:      retention,
+		interval:       interval,
+		logger:         logger.With(slog.String("component", "trash_purger")),
+	}
+}
+
+// Run purges the trash every interval until ctx is done.
+func (p *TrashPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *TrashPurger) purge(ctx context.Context) {
+	purged, err := p.noteRepository.PurgeDeletedNotes(ctx, p.retention)
+	if err != nil {
+		if ctx.Err() == nil {
+			p.logger.Error("failed to purge trash", log.Err(err))
+		}
+		return
+	}
+
+	if purged > 0 {
+		p.logger.Info("purged trash", slog.Int64("notes", purged))
+	}
+}
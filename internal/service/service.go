@@ -2,40 +2,131 @@ package service
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/notifier"
 	"github.com/bojackodin/notes/internal/repository"
-	"github.com/bojackodin/notes/internal/yandex/speller"
+	"github.com/bojackodin/notes/internal/signing"
+	"github.com/bojackodin/notes/internal/speller"
 )
 
 type Auth interface {
 	CreateUser(ctx context.Context, username, password string) (int64, error)
-	GenerateToken(ctx context.Context, username, password string) (string, error)
-	ParseToken(token string) (int64, error)
+	GenerateToken(ctx context.Context, username, password, clientIP string) (Tokens, error)
+	VerifyTwoFactor(ctx context.Context, challengeToken, code, recoveryCode string) (Tokens, error)
+	Refresh(ctx context.Context, refreshToken string) (Tokens, error)
+	SignOut(ctx context.Context, sessionID string) error
+	ParseToken(ctx context.Context, token string) (*TokenClaims, error)
+	JWKS() signing.JWKSet
+}
+
+type User interface {
+	GetUser(ctx context.Context, id int64) (entity.User, error)
+	UpdateUsername(ctx context.Context, id int64, username string) (entity.User, error)
+	DeleteUser(ctx context.Context, id int64, password string) error
+	ExportUser(ctx context.Context, id int64, w io.Writer) error
+}
+
+type Admin interface {
+	ListUsers(ctx context.Context, limit, offset int) ([]entity.UserStats, error)
+	GetUser(ctx context.Context, id int64) (entity.UserStats, error)
+	DisableUser(ctx context.Context, actorID, id int64) (entity.UserStats, error)
+	EnableUser(ctx context.Context, id int64) (entity.UserStats, error)
+	SignOutUser(ctx context.Context, actorID, id int64) error
+	SetRole(ctx context.Context, actorID, id int64, role entity.Role) (entity.UserStats, error)
+}
+
+type Password interface {
+	ChangePassword(ctx context.Context, userID int64, sessionID, oldPassword, newPassword string) error
+	RequestPasswordReset(ctx context.Context, username, clientIP string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+}
+
+type TwoFactor interface {
+	EnrollTwoFactor(ctx context.Context, userID int64) (TwoFactorEnrollment, error)
+	ConfirmTwoFactor(ctx context.Context, userID int64, code string) error
+	DisableTwoFactor(ctx context.Context, userID int64, password, code, recoveryCode string) error
+}
+
+type AccessToken interface {
+	CreateAccessToken(ctx context.Context, userID int64, input CreateAccessTokenInput) (entity.AccessToken, string, error)
+	ListAccessTokens(ctx context.Context, userID int64) ([]entity.AccessToken, error)
+	RevokeAccessToken(ctx context.Context, id, userID int64) error
+	ParseAccessToken(ctx context.Context, token string) (entity.AccessToken, error)
 }
 
 type Note interface {
-	CreateNote(ctx context.Context, title string, userID int64) (int64, error)
-	ListNotes(ctx context.Context, userID int64) ([]entity.Note, error)
+	CreateNote(ctx context.Context, userID int64, input CreateNoteInput) (entity.Note, SpellCheck, error)
+	GetNote(ctx context.Context, id, userID int64) (entity.Note, error)
+	UpdateNote(ctx context.Context, id, userID int64, input UpdateNoteInput) (entity.Note, SpellCheck, error)
+	MoveNote(ctx context.Context, id, userID int64, notebookID *int64, version int) (entity.Note, error)
+	DeleteNote(ctx context.Context, id, userID int64, version int) error
+	RestoreNote(ctx context.Context, id, userID int64) (entity.Note, error)
+	EmptyTrash(ctx context.Context, userID int64) (int64, error)
+	ListNotes(ctx context.Context, userID int64, params ListNotesParams) (NotePage, error)
+	SearchNotes(ctx context.Context, userID int64, params SearchNotesParams) ([]entity.NoteSearchResult, error)
+	ListRevisions(ctx context.Context, noteID, userID int64) ([]entity.NoteRevision, error)
+	GetRevision(ctx context.Context, noteID, userID int64, revision int) (entity.NoteRevision, error)
+	DiffRevisions(ctx context.Context, noteID, userID int64, from, to int) (string, error)
+	RestoreRevision(ctx context.Context, noteID, userID int64, revision, version int) (entity.Note, error)
+}
+
+type Tag interface {
+	ListTags(ctx context.Context, userID int64) ([]entity.Tag, error)
+}
+
+type Notebook interface {
+	CreateNotebook(ctx context.Context, userID int64, name string, parentID *int64) (entity.Notebook, error)
+	GetNotebook(ctx context.Context, id, userID int64) (entity.Notebook, error)
+	ListNotebooks(ctx context.Context, userID int64) ([]entity.Notebook, error)
+	RenameNotebook(ctx context.Context, id, userID int64, name string) (entity.Notebook, error)
+	MoveNotebook(ctx context.Context, id, userID int64, parentID *int64) (entity.Notebook, error)
+	DeleteNotebook(ctx context.Context, id, userID int64, cascade bool) error
 }
 
 type Services struct {
-	Auth Auth
-	Note Note
+	Auth        Auth
+	User        User
+	Admin       Admin
+	Password    Password
+	AccessToken AccessToken
+	TwoFactor   TwoFactor
+	Note        Note
+	Tag         Tag
+	Notebook    Notebook
 }
 
 type ServicesDependencies struct {
 	Repositories *repository.Repositories
 	Speller      speller.Speller
+	SpellPolicy  SpellPolicy
+	Notifier     notifier.Notifier
+	Policy       *Policy
+	SignInLimits SignInLimits
+
+	SigningKeys     *signing.KeySet
+	TokenTTL        time.Duration
+	RefreshTokenTTL time.Duration
+
+	PasswordResetTTL    time.Duration
+	PasswordResetLimits SignInLimits
 
-	Secret   string
-	TokenTTL time.Duration
+	TwoFactorIssuer       string
+	TwoFactorChallengeTTL time.Duration
 }
 
 func NewServices(deps ServicesDependencies) *Services {
 	return &Services{
-		Auth: NewAuthService(deps.Repositories.User, deps.Secret, deps.TokenTTL),
-		Note: NewNoteService(deps.Repositories.Note, deps.Speller),
+		Auth:        NewAuthService(deps.Repositories.User, deps.Repositories.Session, deps.Repositories.TwoFactor, deps.Policy, deps.SignInLimits, deps.SigningKeys, deps.TokenTTL, deps.RefreshTokenTTL, deps.TwoFactorChallengeTTL),
+		User:        NewUserService(deps.Repositories.User, deps.Repositories.Note, deps.Repositories.Notebook, deps.Policy),
+		Admin:       NewAdminService(deps.Repositories.User, deps.Repositories.Session),
+		Password:    NewPasswordService(deps.Repositories.User, deps.Repositories.Session, deps.Repositories.PasswordReset, deps.Notifier, deps.Policy, deps.PasswordResetLimits, deps.PasswordResetTTL),
+		AccessToken: NewAccessTokenService(deps.Repositories.AccessToken),
+		TwoFactor:   NewTwoFactorService(deps.Repositories.TwoFactor, deps.Repositories.User, deps.SignInLimits, deps.TwoFactorIssuer),
+		Note:        NewNoteService(deps.Repositories.Note, deps.Repositories.Notebook, deps.Speller, deps.SpellPolicy),
+		Tag:         NewTagService(deps.Repositories.Tag),
+		Notebook:    NewNotebookService(deps.Repositories.Notebook),
 	}
 }
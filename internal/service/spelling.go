@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/bojackodin/notes/internal/speller"
+)
+
+// SpellPolicy decides what happens to notes with misspellings.
+type SpellPolicy string
+
+const (
+	// SpellPolicyReject fails the write with a *SpellError.
+	SpellPolicyReject SpellPolicy = "reject"
+	// SpellPolicyWarn saves the note as is and reports the misspellings.
+	SpellPolicyWarn SpellPolicy = "warn"
+	// SpellPolicyAutocorrect replaces misspellings with the top suggestion
+	// and reports the replacements. Misspellings without suggestions are
+	// saved as is and reported as warnings.
+	SpellPolicyAutocorrect SpellPolicy = "autocorrect"
+)
+
+// ParseSpellPolicy returns ErrInvalidSpellPolicy for unknown policies.
+func ParseSpellPolicy(s string) (SpellPolicy, error) {
+	switch policy := SpellPolicy(s); policy {
+	case SpellPolicyReject, SpellPolicyWarn, SpellPolicyAutocorrect:
+		return policy, nil
+	default:
+		return "", ErrInvalidSpellPolicy
+	}
+}
+
+// SpellCheck reports the misspellings of a saved note.
+type SpellCheck struct {
+	Warnings    []FieldMisspells
+	Corrections []Correction
+}
+
+// Correction is a misspelling replaced by autocorrection. The misspelling
+// positions refer to the text before correction.
+type Correction struct {
+	Field       string
+	Misspell    speller.Misspell
+	Replacement string
+}
+
+// spellPolicy returns the default policy if policy is empty.
+func (s *NoteService) spellPolicy(policy string) (SpellPolicy, error) {
+	if policy == "" {
+		return s.defaultSpellPolicy, nil
+	}
+
+	return ParseSpellPolicy(policy)
+}
+
+// applySpellPolicy checks the spelling of the fields and applies the policy
+// to the misspellings. It updates the fields in place when autocorrecting.
+func (s *NoteService) applySpellPolicy(ctx context.Context, policy SpellPolicy, fields map[string]string) (SpellCheck, error) {
+	err := s.checkSpelling(ctx, fields)
+
+	var spellErr *SpellError
+	if !errors.As(err, &spellErr) {
+		return SpellCheck{}, err
+	}
+
+	switch policy {
+	case SpellPolicyWarn:
+		return SpellCheck{Warnings: spellErr.Fields}, nil
+	case SpellPolicyAutocorrect:
+		var check SpellCheck
+		for _, f := range spellErr.Fields {
+			text, corrections, remaining := autocorrect(f.Field, fields[f.Field], f.Misspells)
+			fields[f.Field] = text
+
+			check.Corrections = append(check.Corrections, corrections...)
+			if len(remaining) > 0 {
+				check.Warnings = append(check.Warnings, FieldMisspells{Field: f.Field, Misspells: remaining})
+			}
+		}
+		return check, nil
+	default:
+		return SpellCheck{}, err
+	}
+}
+
+// autocorrect replaces every misspelling having a suggestion with the first
+// one. Misspellings that cannot be corrected are returned as remaining.
+func autocorrect(field, text string, misspells []speller.Misspell) (string, []Correction, []speller.Misspell) {
+	var (
+		runes       = []rune(text)
+		corrections []Correction
+		remaining   []speller.Misspell
+		// end is the start of the last replaced misspelling, so that
+		// overlapping ones are skipped.
+		end = len(runes)
+	)
+
+	sorted := slices.Clone(misspells)
+	slices.SortStableFunc(sorted, func(a, b speller.Misspell) int { return b.Pos - a.Pos })
+
+	for _, m := range sorted {
+		if len(m.Suggestions) == 0 || m.Pos < 0 || m.Pos+m.Len > end || string(runes[m.Pos:m.Pos+m.Len]) != m.Word {
+			remaining = append(remaining, m)
+			continue
+		}
+
+		runes = slices.Concat(runes[:m.Pos], []rune(m.Suggestions[0]), runes[m.Pos+m.Len:])
+		end = m.Pos
+
+		corrections = append(corrections, Correction{
+			Field:       field,
+			Misspell:    m,
+			Replacement: m.Suggestions[0],
+		})
+	}
+
+	slices.Reverse(corrections)
+	slices.Reverse(remaining)
+
+	return string(runes), corrections, remaining
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/repository"
+)
+
+const (
+	maxTagLength   = 64
+	maxTagsPerNote = 20
+)
+
+type TagService struct {
+	tagRepository repository.Tag
+}
+
+func NewTagService(tagRepository repository.Tag) *TagService {
+	return &TagService{
+		tagRepository: tagRepository,
+	}
+}
+
+func (s *TagService) ListTags(ctx context.Context, userID int64) ([]entity.Tag, error) {
+	return s.tagRepository.ListTags(ctx, userID)
+}
+
+// normalizeTags lowercases and trims tags, dropping duplicates. The result
+// is sorted so that it can be compared with the tags of a stored note.
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+		}
+		normalized = append(normalized, tag)
+	}
+
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+
+	if len(normalized) > maxTagsPerNote {
+		return nil, fmt.Errorf("%w: at most %d tags per note", ErrInvalidTag, maxTagsPerNote)
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/repository"
+	"github.com/bojackodin/notes/internal/repository/repositoryerror"
+	"github.com/bojackodin/notes/internal/totp"
+)
+
+const (
+	recoveryCodeCount = 10
+	// totpSkew is the number of steps of clock drift tolerated either way.
+	totpSkew = 1
+)
+
+// TwoFactorEnrollment is shown to the user once, when enrolling.
+type TwoFactorEnrollment struct {
+	Secret        string
+	URI           string
+	RecoveryCodes []string
+}
+
+type TwoFactorService struct {
+	twoFactorRepository repository.TwoFactor
+	userRepository      repository.User
+	limits              SignInLimits
+	issuer              string
+}
+
+func NewTwoFactorService(twoFactorRepository repository.TwoFactor, userRepository repository.User, limits SignInLimits, issuer string) *TwoFactorService {
+	return &TwoFactorService{
+		twoFactorRepository: twoFactorRepository,
+		userRepository:      userRepository,
+		limits:              limits,
+		issuer:              issuer,
+	}
+}
+
+// EnrollTwoFactor generates a new secret and recovery codes. The enrollment
+// takes effect once confirmed with a code; enrolling again before that
+// replaces it.
+func (s *TwoFactorService) EnrollTwoFactor(ctx context.Context, userID int64) (TwoFactorEnrollment, error) {
+	user, err := s.userRepository.GetUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return TwoFactorEnrollment{}, ErrUserNotFound
+		}
+		return TwoFactorEnrollment{}, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return TwoFactorEnrollment{}, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return TwoFactorEnrollment{}, err
+	}
+
+	err = s.twoFactorRepository.SaveTwoFactor(ctx, userID, secret, hashes)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrConflict) {
+			return TwoFactorEnrollment{}, ErrTwoFactorEnabled
+		}
+		return TwoFactorEnrollment{}, err
+	}
+
+	return TwoFactorEnrollment{
+		Secret:        secret,
+		URI:           totp.URI(s.issuer, user.Username, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// ConfirmTwoFactor enables two-factor authentication after checking that the
+// user has set up their authenticator correctly.
+func (s *TwoFactorService) ConfirmTwoFactor(ctx context.Context, userID int64, code string) error {
+	tf, err := s.twoFactorRepository.GetTwoFactor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return ErrTwoFactorNotEnrolled
+		}
+		return err
+	}
+	if tf.ConfirmedAt != nil {
+		return ErrTwoFactorEnabled
+	}
+
+	step, ok := totp.Validate(tf.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	err = s.twoFactorRepository.ConfirmTwoFactor(ctx, userID, step)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return ErrTwoFactorEnabled
+		}
+		return err
+	}
+
+	return nil
+}
+
+// DisableTwoFactor removes the enrollment, confirmed or not, after checking
+// the password. A confirmed enrollment also requires a TOTP code or a
+// recovery code, so that a stolen password alone cannot remove the second
+// factor. Code guesses share the throttling of two-factor sign-in.
+func (s *TwoFactorService) DisableTwoFactor(ctx context.Context, userID int64, password, code, recoveryCode string) error {
+	user, err := s.userRepository.GetUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	match, err := matches(user.Password, password)
+	if err != nil {
+		return err
+	}
+	if !match {
+		return ErrWrongPassword
+	}
+
+	tf, err := s.twoFactorRepository.GetTwoFactor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return ErrTwoFactorNotEnrolled
+		}
+		return err
+	}
+
+	if tf.ConfirmedAt != nil {
+		key := "2fa:" + strconv.FormatInt(userID, 10)
+		if err = s.limits.attempt(key, ""); err != nil {
+			return err
+		}
+
+		if err = verifySecondFactor(ctx, s.twoFactorRepository, tf, code, recoveryCode); err != nil {
+			if !errors.Is(err, ErrInvalidTwoFactorCode) {
+				s.limits.cancel(key, "")
+			}
+			return err
+		}
+
+		s.limits.success(key, "")
+	}
+
+	err = s.twoFactorRepository.DeleteTwoFactor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return ErrTwoFactorNotEnrolled
+		}
+		return err
+	}
+
+	return nil
+}
+
+// verifySecondFactor accepts either a TOTP code, which cannot be replayed,
+// or an unused recovery code.
+func verifySecondFactor(ctx context.Context, twoFactorRepository repository.TwoFactor, tf entity.TwoFactor, code, recoveryCode string) error {
+	var err error
+
+	switch {
+	case code != "":
+		step, ok := totp.Validate(tf.Secret, code, time.Now(), totpSkew)
+		if !ok || step <= tf.LastStep {
+			return ErrInvalidTwoFactorCode
+		}
+		err = twoFactorRepository.UseStep(ctx, tf.UserID, step)
+	case recoveryCode != "":
+		err = twoFactorRepository.UseRecoveryCode(ctx, tf.UserID, hashRecoveryCode(recoveryCode))
+	default:
+		return ErrInvalidTwoFactorCode
+	}
+
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrConflict) || errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return ErrInvalidTwoFactorCode
+		}
+		return err
+	}
+
+	return nil
+}
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateRecoveryCodes returns codes formatted as xxxxx-xxxxx and their
+// hashes to store.
+func generateRecoveryCodes() ([]string, [][]byte, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([][]byte, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b)[:10])
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes the code ignoring case, spaces and dashes.
+func hashRecoveryCode(code string) []byte {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hashToken(code)
+}
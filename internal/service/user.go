@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/bojackodin/notes/internal/entity"
+	"github.com/bojackodin/notes/internal/repository"
+	"github.com/bojackodin/notes/internal/repository/repositoryerror"
+)
+
+type UserService struct {
+	userRepository     repository.User
+	noteRepository     repository.Note
+	notebookRepository repository.Notebook
+	policy             *Policy
+}
+
+func NewUserService(userRepository repository.User, noteRepository repository.Note, notebookRepository repository.Notebook, policy *Policy) *UserService {
+	return &UserService{
+		userRepository:     userRepository,
+		noteRepository:     noteRepository,
+		notebookRepository: notebookRepository,
+		policy:             policy,
+	}
+}
+
+func (s *UserService) GetUser(ctx context.Context, id int64) (entity.User, error) {
+	user, err := s.userRepository.GetUserById(ctx, id)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return entity.User{}, ErrUserNotFound
+		}
+		return entity.User{}, err
+	}
+
+	return user, nil
+}
+
+func (s *UserService) UpdateUsername(ctx context.Context, id int64, username string) (entity.User, error) {
+	username = strings.TrimSpace(username)
+	if err := validationError(s.policy.ValidateUsername("username", username)); err != nil {
+		return entity.User{}, err
+	}
+
+	err := s.userRepository.UpdateUsername(ctx, id, username)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositoryerror.ErrDuplicate):
+			return entity.User{}, ErrUserDuplicate
+		case errors.Is(err, repositoryerror.ErrRecordNotFound):
+			return entity.User{}, ErrUserNotFound
+		default:
+			return entity.User{}, err
+		}
+	}
+
+	return s.GetUser(ctx, id)
+}
+
+// DeleteUser deletes the account with all its data after checking the
+// password.
+func (s *UserService) DeleteUser(ctx context.Context, id int64, password string) error {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	match, err := matches(user.Password, password)
+	if err != nil {
+		return err
+	}
+	if !match {
+		return ErrWrongPassword
+	}
+
+	err = s.userRepository.DeleteUser(ctx, id)
+	if err != nil {
+		if errors.Is(err, repositoryerror.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,218 @@
+// Package signing manages the keys JWTs are signed and verified with.
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Supported algorithms.
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Key is a signing key identified by its kid. A key without the private part
+// only verifies tokens, which is how retired keys are kept around until the
+// tokens they signed expire.
+type Key struct {
+	ID     string
+	method jwt.SigningMethod
+	// private and public are the same secret for HMAC keys.
+	private any
+	public  any
+}
+
+// NewHMACKey returns a key for a shared HS256 secret. HMAC keys are never
+// published.
+func NewHMACKey(id, secret string) *Key {
+	return &Key{
+		ID:      id,
+		method:  jwt.SigningMethodHS256,
+		private: []byte(secret),
+		public:  []byte(secret),
+	}
+}
+
+// LoadKey reads a PEM-encoded private key, or a public key for a
+// verification-only key, of the given algorithm.
+func LoadKey(id, algorithm, path string, private bool) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: id}
+
+	switch algorithm {
+	case AlgorithmRS256:
+		key.method = jwt.SigningMethodRS256
+		if private {
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, err
+			}
+			key.private, key.public = privateKey, &privateKey.PublicKey
+		} else {
+			key.public, err = jwt.ParseRSAPublicKeyFromPEM(data)
+			if err != nil {
+				return nil, err
+			}
+		}
+	case AlgorithmEdDSA:
+		key.method = jwt.SigningMethodEdDSA
+		if private {
+			privateKey, err := jwt.ParseEdPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, err
+			}
+			edKey, ok := privateKey.(ed25519.PrivateKey)
+			if !ok {
+				return nil, errors.New("not an Ed25519 private key")
+			}
+			key.private, key.public = edKey, edKey.Public()
+		} else {
+			publicKey, err := jwt.ParseEdPublicKeyFromPEM(data)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := publicKey.(ed25519.PublicKey); !ok {
+				return nil, errors.New("not an Ed25519 public key")
+			}
+			key.public = publicKey
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+
+	return key, nil
+}
+
+// KeySet signs tokens with its current key and verifies them with any of its
+// keys, chosen by the kid header.
+type KeySet struct {
+	current *Key
+	keys    map[string]*Key
+}
+
+// NewKeySet returns a key set signing with the key identified by current.
+func NewKeySet(current string, keys ...*Key) (*KeySet, error) {
+	ks := &KeySet{
+		keys: make(map[string]*Key, len(keys)),
+	}
+
+	for _, key := range keys {
+		if _, ok := ks.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		ks.keys[key.ID] = key
+	}
+
+	ks.current = ks.keys[current]
+	if ks.current == nil {
+		return nil, fmt.Errorf("signing key %q not found", current)
+	}
+	if ks.current.private == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", current)
+	}
+
+	return ks, nil
+}
+
+// Sign signs the claims with the current key. The kid header is omitted for
+// a key with an empty ID, so that tokens signed by a bare shared secret keep
+// the format they had before key rotation was supported.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.current.method, claims)
+	if ks.current.ID != "" {
+		token.Header["kid"] = ks.current.ID
+	}
+
+	return token.SignedString(ks.current.private)
+}
+
+// Keyfunc returns the verification key for the token. It is meant to be
+// passed to jwt.Parse.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	// Never let the token choose the algorithm, e.g. HS256 with a public key.
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("invalid signing method")
+	}
+
+	return key.public, nil
+}
+
+// JWK is a public key in the JSON Web Key format, RFC 7517.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA keys.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 keys.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set. HMAC keys are left out.
+func (ks *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(ks.keys))}
+
+	for _, key := range ks.keys {
+		if jwk, ok := key.jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	slices.SortFunc(set.Keys, func(a, b JWK) int {
+		return strings.Compare(a.KeyID, b.KeyID)
+	})
+
+	return set
+}
+
+func (k *Key) jwk() (JWK, bool) {
+	jwk := JWK{
+		KeyID:     k.ID,
+		Use:       "sig",
+		Algorithm: k.method.Alg(),
+	}
+
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		return JWK{}, false
+	}
+
+	return jwk, true
+}
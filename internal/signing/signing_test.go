@@ -0,0 +1,145 @@
+package signing
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// writeRSAKey writes a new RSA key pair and returns the paths of the private
+// and public keys.
+func writeRSAKey(t *testing.T) (string, string) {
+	t.Helper()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "private.pem")
+	publicPath := filepath.Join(dir, "public.pem")
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	if err := os.WriteFile(privatePath, privatePEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	if err := os.WriteFile(publicPath, publicPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return privatePath, publicPath
+}
+
+func newTestKeySet(t *testing.T) (*KeySet, string) {
+	t.Helper()
+
+	privatePath, publicPath := writeRSAKey(t)
+	key, err := LoadKey("rsa", AlgorithmRS256, privatePath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks, err := NewKeySet("rsa", key, NewHMACKey("hmac", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ks, publicPath
+}
+
+func testClaims() jwt.StandardClaims {
+	return jwt.StandardClaims{Subject: "1", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+}
+
+func TestKeySetSignAndVerify(t *testing.T) {
+	ks, _ := newTestKeySet(t)
+
+	signed, err := ks.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := jwt.ParseWithClaims(signed, &jwt.StandardClaims{}, ks.Keyfunc)
+	if err != nil {
+		t.Fatalf("parse signed token: %v", err)
+	}
+	if kid := token.Header["kid"]; kid != "rsa" {
+		t.Errorf("kid = %v, want rsa", kid)
+	}
+}
+
+func TestKeySetKeyfuncRejectsAlgorithmConfusion(t *testing.T) {
+	ks, publicPath := newTestKeySet(t)
+
+	publicPEM, err := os.ReadFile(publicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An HS256 token signed with the public RSA key as the secret must not
+	// verify against the RS256 key it names.
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	token.Header["kid"] = "rsa"
+	forged, err := token.SignedString(publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := jwt.ParseWithClaims(forged, &jwt.StandardClaims{}, ks.Keyfunc); err == nil {
+		t.Error("HS256 token with an RS256 kid accepted")
+	}
+
+	// Keyfunc itself refuses the key, rather than relying on the HMAC
+	// method to reject a key of the wrong type.
+	parsed, _, err := new(jwt.Parser).ParseUnverified(forged, &jwt.StandardClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, err := ks.Keyfunc(parsed); err == nil {
+		t.Errorf("Keyfunc() = %T, want error", key)
+	}
+}
+
+func TestKeySetKeyfuncKid(t *testing.T) {
+	ks, _ := newTestKeySet(t)
+
+	tests := []struct {
+		name    string
+		kid     any
+		wantErr bool
+	}{
+		{"matching key", "hmac", false},
+		{"unknown key", "other", true},
+		{"missing kid", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+			if tt.kid != nil {
+				token.Header["kid"] = tt.kid
+			}
+			signed, err := token.SignedString([]byte("secret"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = jwt.ParseWithClaims(signed, &jwt.StandardClaims{}, ks.Keyfunc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parse error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package speller
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// Cache stores the misspellings of checked texts by key.
+type Cache interface {
+	Get(key string) ([]Misspell, bool)
+	Set(key string, misspells []Misspell)
+}
+
+// CacheStats counts the checks answered from the cache and the ones passed
+// to the wrapped speller.
+type CacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// CachingSpeller remembers the results of the speller it wraps. Failed
+// checks are not cached.
+type CachingSpeller struct {
+	next    Speller
+	cache   Cache
+	options string
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewCachingSpeller caches the results of next. Options, e.g. the backend
+// and its dictionaries, are part of the cache key, so that results of
+// differently configured spellers never mix.
+func NewCachingSpeller(next Speller, cache Cache, options string) *CachingSpeller {
+	return &CachingSpeller{
+		next:    next,
+		cache:   cache,
+		options: options,
+	}
+}
+
+func (c *CachingSpeller) Check(ctx context.Context, text string) error {
+	key := c.key(text)
+
+	if misspells, ok := c.cache.Get(key); ok {
+		c.hits.Add(1)
+		if len(misspells) > 0 {
+			return &SpellError{slices.Clone(misspells)}
+		}
+		return nil
+	}
+	c.misses.Add(1)
+
+	err := c.next.Check(ctx, text)
+
+	var spellErr *SpellError
+	switch {
+	case err == nil:
+		c.cache.Set(key, nil)
+	case errors.As(err, &spellErr):
+		c.cache.Set(key, slices.Clone(spellErr.Misspells))
+	}
+
+	return err
+}
+
+func (c *CachingSpeller) Stats() CacheStats {
+	return CacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
+
+// key hashes the options and the text with trailing whitespace trimmed,
+// which changes no misspelling position.
+func (c *CachingSpeller) key(text string) string {
+	h := sha256.New()
+	h.Write([]byte(c.options))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.TrimRightFunc(text, unicode.IsSpace)))
+
+	return string(h.Sum(nil))
+}
+
+// MemoryCache is an in-memory Cache evicting the least recently used entries
+// beyond its size and entries older than its TTL, unless the TTL is zero.
+type MemoryCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order holds the entries, the most recently used first.
+	order *list.List
+}
+
+type cacheEntry struct {
+	key       string
+	misspells []Misspell
+	// expiresAt is zero for entries that never expire.
+	expiresAt time.Time
+}
+
+func NewMemoryCache(size int, ttl time.Duration) *MemoryCache {
+	return &MemoryCache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (c *MemoryCache) Get(key string) ([]Misspell, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.misspells, true
+}
+
+func (c *MemoryCache) Set(key string, misspells []Misspell) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = c.now().Add(c.ttl)
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.misspells, entry.expiresAt = misspells, expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{
+		key:       key,
+		misspells: misspells,
+		expiresAt: expiresAt,
+	})
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// Len returns the number of entries, expired ones included.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *MemoryCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,182 @@
+package speller
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCache(size int, ttl time.Duration) (*MemoryCache, *time.Time) {
+	c := NewMemoryCache(size, ttl)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	return c, &now
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	c, _ := newTestCache(2, time.Hour)
+
+	c.Set("a", nil)
+	c.Set("b", nil)
+	// Using a makes b the least recently used entry.
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("Get(a) missed")
+	}
+	c.Set("c", nil)
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	}
+	for _, tt := range tests {
+		if _, ok := c.Get(tt.key); ok != tt.want {
+			t.Errorf("Get(%s) hit = %v, want %v", tt.key, ok, tt.want)
+		}
+	}
+	if got := c.Len(); got != 2 {
+		t.Errorf("Len() = %d, want 2", got)
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		advance time.Duration
+		want    bool
+	}{
+		{"fresh", time.Minute, time.Minute - time.Nanosecond, true},
+		{"expired", time.Minute, time.Minute, false},
+		{"zero TTL never expires", 0, 365 * 24 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, now := newTestCache(10, tt.ttl)
+			want := []Misspell{{Word: "helo"}}
+
+			c.Set("key", want)
+			*now = now.Add(tt.advance)
+
+			got, ok := c.Get("key")
+			if ok != tt.want {
+				t.Fatalf("Get() hit = %v, want %v", ok, tt.want)
+			}
+			if ok && (len(got) != 1 || got[0].Word != "helo") {
+				t.Errorf("Get() = %v, want %v", got, want)
+			}
+			if !ok && c.Len() != 0 {
+				t.Errorf("Len() = %d after expiry, want 0", c.Len())
+			}
+		})
+	}
+}
+
+func TestMemoryCacheSetRefreshes(t *testing.T) {
+	c, now := newTestCache(10, time.Minute)
+
+	c.Set("key", nil)
+	*now = now.Add(30 * time.Second)
+	c.Set("key", []Misspell{{Word: "helo"}})
+	*now = now.Add(45 * time.Second)
+
+	got, ok := c.Get("key")
+	if !ok || len(got) != 1 {
+		t.Errorf("Get() = %v, %v, want the refreshed entry", got, ok)
+	}
+}
+
+func TestCachingSpeller(t *testing.T) {
+	var calls int
+	results := map[string]error{
+		"correct":    nil,
+		"misspelled": &SpellError{Misspells: []Misspell{{Word: "helo", Suggestions: []string{"hello"}}}},
+		"failed":     ErrUnavailable,
+	}
+	next := spellerFunc(func(_ context.Context, text string) error {
+		calls++
+		return results[text]
+	})
+
+	s := NewCachingSpeller(next, NewMemoryCache(10, time.Hour), "test")
+
+	steps := []struct {
+		text      string
+		wantCalls int
+		wantStats CacheStats
+	}{
+		{"correct", 1, CacheStats{Misses: 1}},
+		{"correct", 1, CacheStats{Hits: 1, Misses: 1}},
+		// Trailing whitespace moves no misspelling, so the result is reused.
+		{"correct \n", 1, CacheStats{Hits: 2, Misses: 1}},
+		{"misspelled", 2, CacheStats{Hits: 2, Misses: 2}},
+		{"misspelled", 2, CacheStats{Hits: 3, Misses: 2}},
+		// Failures are not cached.
+		{"failed", 3, CacheStats{Hits: 3, Misses: 3}},
+		{"failed", 4, CacheStats{Hits: 3, Misses: 4}},
+	}
+	for i, step := range steps {
+		err := s.Check(context.Background(), step.text)
+
+		if want := results[strings.TrimSpace(step.text)]; !sameResult(err, want) {
+			t.Errorf("step %d: Check(%q) error = %v, want %v", i, step.text, err, want)
+		}
+		if calls != step.wantCalls {
+			t.Errorf("step %d: %d calls of the wrapped speller, want %d", i, calls, step.wantCalls)
+		}
+		if got := s.Stats(); got != step.wantStats {
+			t.Errorf("step %d: Stats() = %+v, want %+v", i, got, step.wantStats)
+		}
+	}
+}
+
+func TestCachingSpellerCopiesMisspells(t *testing.T) {
+	next := spellerFunc(func(context.Context, string) error {
+		return &SpellError{Misspells: []Misspell{{Word: "helo"}}}
+	})
+	s := NewCachingSpeller(next, NewMemoryCache(10, time.Hour), "test")
+
+	var spellErr *SpellError
+	for range 2 {
+		if err := s.Check(context.Background(), "helo"); !errors.As(err, &spellErr) {
+			t.Fatalf("Check() error = %v, want *SpellError", err)
+		}
+		// Changing a returned result must not change the cached one.
+		spellErr.Misspells[0].Word = "changed"
+	}
+
+	if err := s.Check(context.Background(), "helo"); !errors.As(err, &spellErr) || spellErr.Misspells[0].Word != "helo" {
+		t.Errorf("Check() error = %v, want the cached misspelling of helo", err)
+	}
+}
+
+func TestCachingSpellerOptionsInKey(t *testing.T) {
+	cache := NewMemoryCache(10, time.Hour)
+	a := NewCachingSpeller(spellerFunc(func(context.Context, string) error { return nil }), cache, "a")
+	b := NewCachingSpeller(spellerFunc(func(context.Context, string) error { return nil }), cache, "b")
+
+	_ = a.Check(context.Background(), "text")
+	_ = b.Check(context.Background(), "text")
+
+	if got := b.Stats(); got.Hits != 0 || got.Misses != 1 {
+		t.Errorf("Stats() = %+v, want a miss for other options", got)
+	}
+}
+
+func sameResult(got, want error) bool {
+	var gotSpell, wantSpell *SpellError
+	if errors.As(want, &wantSpell) {
+		return errors.As(got, &gotSpell) && slices.EqualFunc(gotSpell.Misspells, wantSpell.Misspells, func(a, b Misspell) bool {
+			return a.Word == b.Word && slices.Equal(a.Suggestions, b.Suggestions)
+		})
+	}
+
+	return errors.Is(got, want)
+}
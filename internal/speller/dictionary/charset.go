@@ -0,0 +1,50 @@
+package dictionary
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Upper halves of the single-byte charsets used by Hunspell dictionaries.
+var charsets = map[string][]rune{
+	"ISO8859-1":        nil,
+	"KOI8-R":           []rune("─│┌┐└┘├┤┬┴┼▀▄█▌▐░▒▓⌠■∙√≈≤≥\u00a0⌡°²·÷═║╒ё╓╔╕╖╗╘╙╚╛╜╝╞╟╠╡Ё╢╣╤╥╦╧╨╩╪╫╬©юабцдефгхийклмнопярстужвьызшэщчъЮАБЦДЕФГХИЙКЛМНОПЯРСТУЖВЬЫЗШЭЩЧЪ"),
+	"MICROSOFT-CP1251": []rune("ЂЃ‚ѓ„…†‡€‰Љ‹ЊЌЋЏђ‘’“”•–—\ufffd™љ›њќћџ\u00a0ЎўЈ¤Ґ¦§Ё©Є«¬\u00ad®Ї°±Ііґµ¶·ё№є»јЅѕїАБВГДЕЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯабвгдежзийклмнопрстуфхцчшщъыьэюя"),
+}
+
+// decode converts text in the charset named by a SET directive to UTF-8.
+func decode(data []byte, charset string) (string, error) {
+	charset = strings.ToUpper(charset)
+	switch charset {
+	case "", "UTF-8":
+		if !utf8.Valid(data) {
+			return "", fmt.Errorf("invalid UTF-8")
+		}
+		return string(data), nil
+	case "WINDOWS-1251", "CP1251":
+		charset = "MICROSOFT-CP1251"
+	case "ISO-8859-1", "LATIN1":
+		charset = "ISO8859-1"
+	}
+
+	upper, ok := charsets[charset]
+	if !ok {
+		return "", fmt.Errorf("unsupported charset %q", charset)
+	}
+
+	var b strings.Builder
+	b.Grow(len(data))
+	for _, c := range data {
+		switch {
+		case c < 0x80:
+			b.WriteByte(c)
+		case upper == nil:
+			b.WriteRune(rune(c))
+		default:
+			b.WriteRune(upper[c-0x80])
+		}
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,553 @@
+package dictionary
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// dictionary is a set of words, each optionally carrying the flags of the
+// affix classes that apply to it, in the Hunspell .dic/.aff format. A word
+// listed more than once is a set of homonyms with their own flags. Affixes
+// are stripped when looking words up instead of being expanded on load, so
+// heavily inflected languages such as Russian stay small in memory.
+type dictionary struct {
+	words map[string][][]string
+	// try holds the characters tried when suggesting corrections, the
+	// most frequent ones first.
+	try []rune
+
+	flagMode      string
+	aliases       [][]string
+	forbiddenWord string
+	needAffix     string
+
+	// Affix rules indexed by the text they add.
+	prefixes map[string][]*affixRule
+	suffixes map[string][]*affixRule
+	// maxPrefix and maxSuffix are the lengths in bytes of the longest
+	// added texts.
+	maxPrefix int
+	maxSuffix int
+}
+
+type affixRule struct {
+	flag  string
+	cross bool
+	strip string
+	add   string
+	cond  condition
+}
+
+func newDictionary() *dictionary {
+	return &dictionary{
+		words:    make(map[string][][]string),
+		prefixes: make(map[string][]*affixRule),
+		suffixes: make(map[string][]*affixRule),
+	}
+}
+
+// loadHunspell loads the .dic file at path together with the .aff file next
+// to it.
+func loadHunspell(path string) (*dictionary, error) {
+	d := newDictionary()
+
+	affPath := strings.TrimSuffix(path, ".dic") + ".aff"
+	affData, err := os.ReadFile(affPath)
+	if err != nil {
+		return nil, err
+	}
+
+	charset := affixCharset(affData)
+
+	aff, err := decode(affData, charset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", affPath, err)
+	}
+	if err = d.parseAffixes(aff); err != nil {
+		return nil, fmt.Errorf("%s: %w", affPath, err)
+	}
+
+	dicData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dic, err := decode(dicData, charset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err = d.parseWords(dic); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return d, nil
+}
+
+// loadWordList loads a plain list with a word per line. Empty lines and
+// lines starting with # are skipped.
+func loadWordList(path string) (*dictionary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("%s: invalid UTF-8", path)
+	}
+
+	d := newDictionary()
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		d.words[normalizeApostrophes(word)] = [][]string{nil}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	d.try = d.alphabet()
+
+	return d, nil
+}
+
+// affixCharset returns the value of the SET directive, which is ASCII in any
+// charset.
+func affixCharset(aff []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(aff))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "SET" {
+			return fields[1]
+		}
+	}
+
+	return ""
+}
+
+func (d *dictionary) parseAffixes(aff string) error {
+	lines := strings.Split(aff, "\n")
+
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch fields[0] {
+		case "TRY":
+			if len(fields) < 2 {
+				return fmt.Errorf("line %d: missing characters", i+1)
+			}
+			d.try = []rune(fields[1])
+		case "FLAG":
+			if len(fields) < 2 {
+				return fmt.Errorf("line %d: missing flag type", i+1)
+			}
+			d.flagMode = fields[1]
+		case "FORBIDDENWORD", "NEEDAFFIX":
+			if len(fields) < 2 {
+				return fmt.Errorf("line %d: missing flag", i+1)
+			}
+			if fields[0] == "FORBIDDENWORD" {
+				d.forbiddenWord = fields[1]
+			} else {
+				d.needAffix = fields[1]
+			}
+		case "AF":
+			// The header holds the number of aliases, listed on the
+			// following AF lines.
+			if len(fields) < 2 {
+				return fmt.Errorf("line %d: missing alias count", i+1)
+			}
+			count, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return fmt.Errorf("line %d: invalid alias count: %w", i+1, err)
+			}
+
+			for j := 0; j < count; j++ {
+				i++
+				if i >= len(lines) {
+					return fmt.Errorf("line %d: missing aliases", i)
+				}
+
+				alias := strings.Fields(lines[i])
+				if len(alias) < 2 || alias[0] != "AF" {
+					return fmt.Errorf("line %d: invalid alias", i+1)
+				}
+				d.aliases = append(d.aliases, d.splitFlags(alias[1]))
+			}
+		case "PFX", "SFX":
+			if len(fields) < 4 {
+				return fmt.Errorf("line %d: invalid affix header", i+1)
+			}
+
+			flag, cross := fields[1], fields[2] == "Y"
+			count, err := strconv.Atoi(fields[3])
+			if err != nil {
+				return fmt.Errorf("line %d: invalid affix count: %w", i+1, err)
+			}
+
+			for j := 0; j < count; j++ {
+				i++
+				if i >= len(lines) {
+					return fmt.Errorf("line %d: missing affix rules", i)
+				}
+
+				rule, err := parseAffixRule(fields[0], flag, cross, lines[i])
+				if err != nil {
+					return fmt.Errorf("line %d: %w", i+1, err)
+				}
+
+				if fields[0] == "PFX" {
+					d.prefixes[rule.add] = append(d.prefixes[rule.add], rule)
+					d.maxPrefix = max(d.maxPrefix, len(rule.add))
+				} else {
+					d.suffixes[rule.add] = append(d.suffixes[rule.add], rule)
+					d.maxSuffix = max(d.maxSuffix, len(rule.add))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseAffixRule parses a line like "SFX D y ied [^aeiou]y".
+func parseAffixRule(kind, flag string, cross bool, line string) (*affixRule, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[0] != kind || fields[1] != flag {
+		return nil, fmt.Errorf("invalid %s rule %q", kind, line)
+	}
+
+	rule := &affixRule{
+		flag:  flag,
+		cross: cross,
+		strip: fields[2],
+		add:   fields[3],
+	}
+	if rule.strip == "0" {
+		rule.strip = ""
+	}
+	// Continuation classes of two-level affixes are not supported.
+	if k := strings.IndexByte(rule.add, '/'); k >= 0 {
+		rule.add = rule.add[:k]
+	}
+	if rule.add == "0" {
+		rule.add = ""
+	}
+
+	cond := "."
+	if len(fields) > 4 {
+		cond = fields[4]
+	}
+
+	var err error
+	if rule.cond, err = parseCondition(cond); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func (d *dictionary) parseWords(dic string) error {
+	lines := strings.Split(dic, "\n")
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		// The first line holds the approximate number of words.
+		if line == "" || strings.HasPrefix(line, "#") || (i == 0 && isNumber(line)) {
+			continue
+		}
+
+		// Morphological fields follow after whitespace.
+		if k := strings.IndexAny(line, " \t"); k >= 0 {
+			line = line[:k]
+		}
+
+		word, flags := line, ""
+		if k := unescapedSlash(line); k >= 0 {
+			word, flags = line[:k], line[k+1:]
+		}
+		word = normalizeApostrophes(strings.ReplaceAll(word, `\/`, "/"))
+
+		var wordFlags []string
+		if flags != "" {
+			if len(d.aliases) > 0 && isNumber(flags) {
+				n, _ := strconv.Atoi(flags)
+				if n < 1 || n > len(d.aliases) {
+					return fmt.Errorf("line %d: unknown flag alias %d", i+1, n)
+				}
+				wordFlags = d.aliases[n-1]
+			} else {
+				wordFlags = d.splitFlags(flags)
+			}
+		}
+
+		// Homonyms are listed on separate lines with different flags.
+		d.words[word] = append(d.words[word], wordFlags)
+	}
+
+	if len(d.try) == 0 {
+		d.try = d.alphabet()
+	}
+
+	return nil
+}
+
+// alphabet returns the lower case letters of the words, for dictionaries
+// without a TRY directive.
+func (d *dictionary) alphabet() []rune {
+	seen := make(map[rune]bool)
+	for word := range d.words {
+		for _, r := range strings.ToLower(word) {
+			if unicode.IsLetter(r) {
+				seen[r] = true
+			}
+		}
+	}
+
+	runes := make([]rune, 0, len(seen))
+	for r := range seen {
+		runes = append(runes, r)
+	}
+	slices.Sort(runes)
+
+	return runes
+}
+
+func (d *dictionary) splitFlags(flags string) []string {
+	switch d.flagMode {
+	case "long":
+		result := make([]string, 0, len(flags)/2)
+		for len(flags) >= 2 {
+			result = append(result, flags[:2])
+			flags = flags[2:]
+		}
+		return result
+	case "num":
+		return strings.Split(flags, ",")
+	default:
+		// A flag is a single character, or a single rune in the UTF-8 mode.
+		result := make([]string, 0, len(flags))
+		for _, r := range flags {
+			result = append(result, string(r))
+		}
+		return result
+	}
+}
+
+// check reports whether the dictionary knows the word, as is or with any of
+// its affixes stripped.
+func (d *dictionary) check(word string) bool {
+	if d.hasRoot(word) {
+		return true
+	}
+
+	return d.checkSuffixed(word, nil) || d.checkPrefixed(word)
+}
+
+// checkSuffixed strips suffixes from the word. With a prefix stripped before,
+// both must allow cross products and the root must carry both flags.
+func (d *dictionary) checkSuffixed(word string, prefix *affixRule) bool {
+	for n := min(len(word)-1, d.maxSuffix); n >= 0; n-- {
+		for _, rule := range d.suffixes[word[len(word)-n:]] {
+			if prefix != nil && !(rule.cross && prefix.cross) {
+				continue
+			}
+
+			root := word[:len(word)-n] + rule.strip
+			if !rule.cond.matchSuffix(root) {
+				continue
+			}
+
+			if prefix == nil && d.hasRoot(root, rule.flag) {
+				return true
+			}
+			if prefix != nil && d.hasRoot(root, rule.flag, prefix.flag) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (d *dictionary) checkPrefixed(word string) bool {
+	for n := min(len(word)-1, d.maxPrefix); n >= 0; n-- {
+		for _, rule := range d.prefixes[word[:n]] {
+			root := rule.strip + word[n:]
+			if !rule.cond.matchPrefix(root) {
+				continue
+			}
+
+			if d.hasRoot(root, rule.flag) {
+				return true
+			}
+
+			if rule.cross && d.checkSuffixed(root, rule) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasRoot reports whether a homonym of the root carries all the affix flags
+// and none is forbidden. Without flags the root must be a word on its own,
+// without the NEEDAFFIX flag.
+func (d *dictionary) hasRoot(root string, affixFlags ...string) bool {
+	homonyms, ok := d.words[root]
+	if !ok {
+		return false
+	}
+	if slices.ContainsFunc(homonyms, func(flags []string) bool { return d.has(flags, d.forbiddenWord) }) {
+		return false
+	}
+
+	return slices.ContainsFunc(homonyms, func(flags []string) bool {
+		if len(affixFlags) == 0 {
+			return !d.has(flags, d.needAffix)
+		}
+		for _, flag := range affixFlags {
+			if !d.has(flags, flag) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (d *dictionary) has(flags []string, flag string) bool {
+	if flag == "" {
+		return false
+	}
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// condition is a simplified regular expression over the start or end of a
+// root, made of characters, dots and bracketed character classes.
+type condition []charClass
+
+type charClass struct {
+	any    bool
+	negate bool
+	chars  []rune
+}
+
+func (c charClass) match(r rune) bool {
+	if c.any {
+		return true
+	}
+	for _, ch := range c.chars {
+		if ch == r {
+			return !c.negate
+		}
+	}
+
+	return c.negate
+}
+
+func parseCondition(s string) (condition, error) {
+	if s == "." {
+		return nil, nil
+	}
+
+	var cond condition
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '.':
+			cond = append(cond, charClass{any: true})
+		case '[':
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated condition %q", s)
+			}
+
+			class := charClass{chars: runes[i+1 : end]}
+			if len(class.chars) > 0 && class.chars[0] == '^' {
+				class.negate, class.chars = true, class.chars[1:]
+			}
+			cond = append(cond, class)
+			i = end
+		default:
+			cond = append(cond, charClass{chars: runes[i : i+1]})
+		}
+	}
+
+	return cond, nil
+}
+
+func (c condition) matchPrefix(root string) bool {
+	runes := []rune(root)
+	if len(runes) < len(c) {
+		return false
+	}
+	for i, class := range c {
+		if !class.match(runes[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c condition) matchSuffix(root string) bool {
+	runes := []rune(root)
+	if len(runes) < len(c) {
+		return false
+	}
+	offset := len(runes) - len(c)
+	for i, class := range c {
+		if !class.match(runes[offset+i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func unescapedSlash(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '/':
+			if i > 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+func normalizeApostrophes(s string) string {
+	return strings.ReplaceAll(s, "’", "'")
+}
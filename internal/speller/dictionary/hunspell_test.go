@@ -0,0 +1,297 @@
+package dictionary
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeHunspell writes the .aff and .dic fixtures, encoded in the charset,
+// and returns the path of the .dic file.
+func writeHunspell(t *testing.T, charset, aff, dic string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.dic")
+
+	if err := os.WriteFile(filepath.Join(dir, "test.aff"), encode(t, aff, charset), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, encode(t, dic, charset), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// encode is the inverse of decode for the single-byte charsets.
+func encode(t *testing.T, s, charset string) []byte {
+	t.Helper()
+
+	upper, ok := charsets[charset]
+	if !ok {
+		return []byte(s)
+	}
+
+	var b []byte
+	for _, r := range s {
+		switch i := slices.Index(upper, r); {
+		case r < 0x80:
+			b = append(b, byte(r))
+		case upper == nil && r < 0x100:
+			b = append(b, byte(r))
+		case i >= 0:
+			b = append(b, byte(0x80+i))
+		default:
+			t.Fatalf("%q cannot be encoded in %s", r, charset)
+		}
+	}
+
+	return b
+}
+
+const englishAff = `SET UTF-8
+TRY esianrtolcdugmphbyfvkwz
+
+PFX A Y 1
+PFX A   0     re         .
+
+SFX D Y 4
+SFX D   0     d          e
+SFX D   y     ied        [^aeiou]y
+SFX D   0     ed         [^ey]
+SFX D   0     ed         [aeiou]y
+
+SFX S Y 1
+SFX S   y     ies        [^aeiou]y
+
+FORBIDDENWORD !
+NEEDAFFIX X
+`
+
+const englishDic = `6
+create/AD
+carry/DS
+work/D
+worky/!
+bound/X
+bound/D
+`
+
+func TestDictionaryCheck(t *testing.T) {
+	d, err := loadHunspell(writeHunspell(t, "", englishAff, englishDic))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		word string
+		want bool
+	}{
+		{"create", true},
+		{"created", true},
+		{"recreate", true},
+		// Both affixes allow cross products.
+		{"recreated", true},
+		{"carried", true},
+		{"carries", true},
+		{"carryed", false},
+		{"worked", true},
+		// The prefix does not apply to work.
+		{"rework", false},
+		{"worky", false},
+		// Homonyms merge their flags, so NEEDAFFIX does not hide the
+		// other entry.
+		{"bound", true},
+		{"bounded", true},
+		{"creat", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := d.check(tt.word); got != tt.want {
+				t.Errorf("check(%q) = %v, want %v", tt.word, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDictionaryNeedAffix(t *testing.T) {
+	d, err := loadHunspell(writeHunspell(t, "", englishAff, "1\nbound/XD\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.check("bound") {
+		t.Error("check(bound) = true, want false for a NEEDAFFIX root")
+	}
+	if !d.check("bounded") {
+		t.Error("check(bounded) = false, want true")
+	}
+}
+
+// Russian affixes are several bytes long, so stripping must not split runes.
+const russianAff = `SET KOI8-R
+TRY оеаинтсрвлкмдпуяызьбгчйхжшюцщэфъё
+
+PFX P Y 1
+PFX P   0     пере       .
+
+SFX L Y 3
+SFX L   а     ы          [^гкх]а
+SFX L   а     и          [гкх]а
+SFX L   а     ой         а
+
+SFX V Y 2
+SFX V   ть    л          ть
+SFX V   ть    ла         ть
+`
+
+const russianDic = `3
+книга/L
+мама/L
+писать/PV
+`
+
+func TestDictionaryCheckCyrillic(t *testing.T) {
+	for _, charset := range []string{"KOI8-R", "MICROSOFT-CP1251", "UTF-8"} {
+		t.Run(charset, func(t *testing.T) {
+			aff := russianAff
+			if charset != "KOI8-R" {
+				aff = "SET " + charset + russianAff[len("SET KOI8-R"):]
+			}
+
+			d, err := loadHunspell(writeHunspell(t, charset, aff, russianDic))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tests := []struct {
+				word string
+				want bool
+			}{
+				{"книга", true},
+				{"книги", true},
+				{"книгы", false},
+				{"книгой", true},
+				{"мамы", true},
+				{"мами", false},
+				{"писал", true},
+				{"писала", true},
+				{"переписать", true},
+				{"переписала", true},
+				{"перекнига", false},
+				{"писало", false},
+			}
+			for _, tt := range tests {
+				if got := d.check(tt.word); got != tt.want {
+					t.Errorf("check(%q) = %v, want %v", tt.word, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDictionaryFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		aff  string
+		dic  string
+		word string
+	}{
+		{
+			name: "long",
+			aff:  "FLAG long\nSFX Aa Y 1\nSFX Aa 0 s .\n",
+			dic:  "1\ncat/BbAa\n",
+			word: "cats",
+		},
+		{
+			name: "num",
+			aff:  "FLAG num\nSFX 101 Y 1\nSFX 101 0 s .\n",
+			dic:  "1\ncat/7,101\n",
+			word: "cats",
+		},
+		{
+			name: "UTF-8",
+			aff:  "SET UTF-8\nFLAG UTF-8\nSFX Ж Y 1\nSFX Ж 0 s .\n",
+			dic:  "1\ncat/Ж\n",
+			word: "cats",
+		},
+		{
+			name: "aliases",
+			aff:  "AF 2\nAF B\nAF AB\nSFX A Y 1\nSFX A 0 s .\n",
+			dic:  "2\ncat/2\ndog/1\n",
+			word: "cats",
+		},
+		{
+			name: "escaped slash",
+			aff:  "SFX A Y 1\nSFX A 0 s .\n",
+			dic:  "1\nand\\/or/A\n",
+			word: "and/ors",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := loadHunspell(writeHunspell(t, "", tt.aff, tt.dic))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !d.check(tt.word) {
+				t.Errorf("check(%q) = false, want true", tt.word)
+			}
+		})
+	}
+}
+
+func TestLoadHunspellErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		aff  string
+		dic  string
+	}{
+		{"missing rules", "SFX A Y 2\nSFX A 0 s .\n", "1\ncat/A\n"},
+		{"invalid count", "SFX A Y x\n", "1\ncat\n"},
+		{"wrong flag", "SFX A Y 1\nSFX B 0 s .\n", "1\ncat\n"},
+		{"unterminated condition", "SFX A Y 1\nSFX A 0 s [ab\n", "1\ncat\n"},
+		{"unknown alias", "AF 1\nAF A\n", "1\ncat/2\n"},
+		{"unsupported charset", "SET ISO8859-5\n", "1\ncat\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadHunspell(writeHunspell(t, "", tt.aff, tt.dic)); err == nil {
+				t.Error("loadHunspell() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		charset string
+		data    []byte
+		want    string
+	}{
+		{"", []byte("привет"), "привет"},
+		{"KOI8-R", []byte{0xd0, 0xd2, 0xc9, 0xd7, 0xc5, 0xd4, 0xa3}, "приветё"},
+		{"koi8-r", []byte{0xf0}, "П"},
+		{"CP1251", []byte{0xef, 0xf0, 0xe8, 0xe2, 0xe5, 0xf2, 0xb8}, "приветё"},
+		{"ISO8859-1", []byte{'c', 'a', 'f', 0xe9}, "café"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.charset, func(t *testing.T) {
+			got, err := decode(tt.data, tt.charset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("decode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := decode([]byte{0xff}, "UTF-8"); err == nil {
+		t.Error("decode() of invalid UTF-8 error = nil, want error")
+	}
+}
@@ -0,0 +1,295 @@
+// Package dictionary checks spelling offline against Hunspell dictionaries
+// and plain word lists.
+package dictionary
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bojackodin/notes/internal/speller"
+)
+
+const (
+	maxSuggestions = 5
+	// maxMisspells bounds the misspellings reported by a check. Words past
+	// them are not checked, so that a long text full of junk cannot make a
+	// check expensive.
+	maxMisspells = 50
+	// maxSuggestLength is the length in runes of the longest word that gets
+	// suggestions, whose number grows with the length.
+	maxSuggestLength = 30
+)
+
+// Speller checks spelling offline against local dictionaries. A word is
+// correct if any of the dictionaries knows it.
+type Speller struct {
+	dictionaries []*dictionary
+}
+
+// New loads the dictionaries at paths. Files with the .dic extension are
+// Hunspell dictionaries, read together with the .aff file of the same name;
+// any other file is a plain UTF-8 word list.
+func New(paths ...string) (*Speller, error) {
+	s := &Speller{}
+
+	for _, path := range paths {
+		var (
+			d   *dictionary
+			err error
+		)
+		if filepath.Ext(path) == ".dic" {
+			d, err = loadHunspell(path)
+		} else {
+			d, err = loadWordList(path)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		s.dictionaries = append(s.dictionaries, d)
+	}
+
+	return s, nil
+}
+
+func (s *Speller) Check(ctx context.Context, text string) error {
+	var misspells []speller.Misspell
+
+	for _, w := range words(text) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if s.checkWord(w.text) {
+			continue
+		}
+
+		if len(misspells) == maxMisspells {
+			// Report the word where checking stopped.
+			misspells = append(misspells, speller.Misspell{
+				Code: speller.CodeTooManyErrors,
+				Pos:  w.pos,
+				Row:  w.row,
+				Col:  w.col,
+				Len:  utf8.RuneCountInString(w.text),
+				Word: w.text,
+			})
+			break
+		}
+
+		misspells = append(misspells, speller.Misspell{
+			Code:        speller.CodeUnknownWord,
+			Pos:         w.pos,
+			Row:         w.row,
+			Col:         w.col,
+			Len:         utf8.RuneCountInString(w.text),
+			Word:        w.text,
+			Suggestions: s.suggest(w.text),
+		})
+	}
+
+	if len(misspells) > 0 {
+		return &speller.SpellError{Misspells: misspells}
+	}
+
+	return nil
+}
+
+// checkWord accepts hyphenated compounds whose parts are all correct.
+func (s *Speller) checkWord(word string) bool {
+	if s.known(word) {
+		return true
+	}
+	if !strings.Contains(word, "-") {
+		return false
+	}
+
+	for _, part := range strings.Split(word, "-") {
+		if !s.known(part) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// known looks the word up as written and, if capitalized or in upper case,
+// in lower and title case, so that sentence starts and headings are accepted
+// while proper nouns keep requiring their capital letter.
+func (s *Speller) known(word string) bool {
+	word = normalizeApostrophes(word)
+
+	variants := []string{word}
+	if lower := strings.ToLower(word); lower != word {
+		variants = append(variants, lower)
+		if strings.ToUpper(word) == word {
+			r, size := utf8.DecodeRuneInString(lower)
+			variants = append(variants, string(unicode.ToUpper(r))+lower[size:])
+		}
+	}
+
+	for _, variant := range variants {
+		for _, d := range s.dictionaries {
+			if d.check(variant) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// suggest returns up to maxSuggestions known words a single edit away from
+// the word: a replaced, swapped, missing or extra character. Words longer
+// than maxSuggestLength get none.
+func (s *Speller) suggest(word string) []string {
+	lower := []rune(strings.ToLower(normalizeApostrophes(word)))
+	if len(lower) > maxSuggestLength {
+		return nil
+	}
+
+	var try []rune
+	for _, d := range s.dictionaries {
+		for _, r := range d.try {
+			if !slices.Contains(try, r) {
+				try = append(try, r)
+			}
+		}
+	}
+
+	var candidates [][]rune
+	for i := range lower {
+		for _, r := range try {
+			if r != lower[i] {
+				candidates = append(candidates, slices.Concat(lower[:i], []rune{r}, lower[i+1:]))
+			}
+		}
+	}
+	for i := 0; i+1 < len(lower); i++ {
+		if lower[i] != lower[i+1] {
+			candidates = append(candidates, slices.Concat(lower[:i], []rune{lower[i+1], lower[i]}, lower[i+2:]))
+		}
+	}
+	for i := range lower {
+		if len(lower) > 1 {
+			candidates = append(candidates, slices.Concat(lower[:i], lower[i+1:]))
+		}
+	}
+	for i := 0; i <= len(lower); i++ {
+		for _, r := range try {
+			candidates = append(candidates, slices.Concat(lower[:i], []rune{r}, lower[i:]))
+		}
+	}
+
+	var suggestions []string
+	for _, candidate := range candidates {
+		suggestion := matchCase(string(candidate), word)
+		if slices.Contains(suggestions, suggestion) || !s.known(suggestion) {
+			continue
+		}
+
+		suggestions = append(suggestions, suggestion)
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+	}
+
+	return suggestions
+}
+
+// matchCase capitalizes the lower case suggestion like the word.
+func matchCase(suggestion, word string) string {
+	first, _ := utf8.DecodeRuneInString(word)
+	switch {
+	case strings.ToUpper(word) == word && utf8.RuneCountInString(word) > 1:
+		return strings.ToUpper(suggestion)
+	case unicode.IsUpper(first):
+		r, size := utf8.DecodeRuneInString(suggestion)
+		return string(unicode.ToUpper(r)) + suggestion[size:]
+	default:
+		return suggestion
+	}
+}
+
+type word struct {
+	text string
+	// pos is the offset of the word in runes, row and col its line and
+	// offset within the line.
+	pos      int
+	row, col int
+}
+
+// words splits the text into words of letters, joined by inner apostrophes
+// and hyphens. Words with digits, URLs and e-mail addresses are skipped.
+func words(text string) []word {
+	var (
+		result    []word
+		runes     = []rune(text)
+		row       int
+		lineStart int
+	)
+
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			if runes[i] == '\n' {
+				row, lineStart = row+1, i+1
+			}
+			i++
+			continue
+		}
+
+		// Skip whole tokens that are links or addresses.
+		end := i
+		for end < len(runes) && !unicode.IsSpace(runes[end]) {
+			end++
+		}
+		if token := string(runes[i:end]); strings.Contains(token, "://") || strings.HasPrefix(token, "www.") || strings.Contains(token, "@") {
+			i = end
+			continue
+		}
+
+		for i < end {
+			if !isWordRune(runes[i]) {
+				i++
+				continue
+			}
+
+			start := i
+			i = wordEnd(runes[:end], i)
+
+			w := runes[start:i]
+			if !slices.ContainsFunc(w, unicode.IsDigit) {
+				result = append(result, word{text: string(w), pos: start, row: row, col: start - lineStart})
+			}
+		}
+	}
+
+	return result
+}
+
+// wordEnd returns the end of the word starting at i.
+func wordEnd(runes []rune, i int) int {
+	for ; i < len(runes); i++ {
+		if isWordRune(runes[i]) {
+			continue
+		}
+		if isJoiner(runes[i]) && i+1 < len(runes) && isWordRune(runes[i+1]) {
+			continue
+		}
+		break
+	}
+
+	return i
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+func isJoiner(r rune) bool {
+	return r == '\'' || r == '’' || r == '-'
+}
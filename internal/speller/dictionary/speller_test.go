@@ -0,0 +1,162 @@
+package dictionary
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bojackodin/notes/internal/speller"
+)
+
+func newTestSpeller(t *testing.T) *Speller {
+	t.Helper()
+
+	words := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(words, []byte("# words\nLondon\nit's\nwell\nknown\nand\nin\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(writeHunspell(t, "", englishAff, englishDic), words)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestSpellerCheck(t *testing.T) {
+	s := newTestSpeller(t)
+
+	tests := []struct {
+		name string
+		text string
+		want []speller.Misspell
+	}{
+		{
+			name: "correct",
+			text: "Created, carried and worked in London.",
+		},
+		{
+			name: "upper case",
+			text: "CREATED IN LONDON",
+		},
+		{
+			name: "apostrophes and hyphens",
+			text: "It’s well-known",
+		},
+		{
+			name: "proper noun in lower case",
+			text: "london",
+			want: []speller.Misspell{
+				{Code: speller.CodeUnknownWord, Pos: 0, Len: 6, Word: "london", Suggestions: []string{}},
+			},
+		},
+		{
+			name: "skipped tokens",
+			text: "work2 https://exampel.com me@exampel.com",
+		},
+		{
+			name: "positions",
+			text: "created\nwell, craeted carryed",
+			want: []speller.Misspell{
+				{Code: speller.CodeUnknownWord, Pos: 14, Row: 1, Col: 6, Len: 7, Word: "craeted", Suggestions: []string{"created"}},
+				{Code: speller.CodeUnknownWord, Pos: 22, Row: 1, Col: 14, Len: 7, Word: "carryed", Suggestions: []string{"carried"}},
+			},
+		},
+		{
+			name: "suggestion case",
+			text: "Crated WRK",
+			want: []speller.Misspell{
+				{Code: speller.CodeUnknownWord, Pos: 0, Len: 6, Word: "Crated", Suggestions: []string{"Created"}},
+				{Code: speller.CodeUnknownWord, Pos: 7, Col: 7, Len: 3, Word: "WRK", Suggestions: []string{"WORK"}},
+			},
+		},
+		{
+			name: "cyrillic positions",
+			text: "ёж craeted",
+			want: []speller.Misspell{
+				{Code: speller.CodeUnknownWord, Pos: 0, Len: 2, Word: "ёж", Suggestions: []string{}},
+				{Code: speller.CodeUnknownWord, Pos: 3, Col: 3, Len: 7, Word: "craeted", Suggestions: []string{"created"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Check(context.Background(), tt.text)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var spellErr *speller.SpellError
+			if !errors.As(err, &spellErr) {
+				t.Fatalf("Check() error = %v, want *SpellError", err)
+			}
+			if !equalMisspells(spellErr.Misspells, tt.want) {
+				t.Errorf("Check() misspells = %+v, want %+v", spellErr.Misspells, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpellerCheckLimits(t *testing.T) {
+	s := newTestSpeller(t)
+
+	text := strings.Repeat("craeted ", maxMisspells+10)
+
+	var spellErr *speller.SpellError
+	if err := s.Check(context.Background(), text); !errors.As(err, &spellErr) {
+		t.Fatalf("Check() error = %v, want *SpellError", err)
+	}
+
+	misspells := spellErr.Misspells
+	if len(misspells) != maxMisspells+1 {
+		t.Fatalf("Check() reported %d misspellings, want %d", len(misspells), maxMisspells+1)
+	}
+	if last := misspells[maxMisspells]; last.Code != speller.CodeTooManyErrors || last.Pos != maxMisspells*8 {
+		t.Errorf("Check() last misspelling = %+v, want too_many_errors at %d", last, maxMisspells*8)
+	}
+
+	if got := s.suggest(strings.Repeat("x", maxSuggestLength) + "created"); got != nil {
+		t.Errorf("suggest() of a long word = %v, want nil", got)
+	}
+}
+
+func TestSpellerCheckCanceled(t *testing.T) {
+	s := newTestSpeller(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := s.Check(ctx, "created"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Check() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestNewMissingAffixFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.dic")
+	if err := os.WriteFile(path, []byte("1\ncat\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(path); err == nil {
+		t.Error("New() error = nil, want error")
+	}
+}
+
+// equalMisspells compares misspellings treating nil and empty suggestions as
+// equal.
+func equalMisspells(got, want []speller.Misspell) bool {
+	return slices.EqualFunc(got, want, func(a, b speller.Misspell) bool {
+		suggestionsEqual := slices.Equal(a.Suggestions, b.Suggestions)
+		a.Suggestions, b.Suggestions = nil, nil
+		return suggestionsEqual && reflect.DeepEqual(a, b)
+	})
+}
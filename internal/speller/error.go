@@ -0,0 +1,67 @@
+package speller
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorCode is the kind of a misspelling. The codes are the ones of
+// Yandex.Speller, so its responses decode as they are.
+type ErrorCode int
+
+const (
+	CodeUnknownWord    ErrorCode = 1
+	CodeRepeatWord     ErrorCode = 2
+	CodeCapitalization ErrorCode = 3
+	// CodeTooManyErrors is reported once the text has too many errors to
+	// check it further.
+	CodeTooManyErrors ErrorCode = 4
+)
+
+func (c ErrorCode) String() string {
+	switch c {
+	case CodeUnknownWord:
+		return "unknown_word"
+	case CodeRepeatWord:
+		return "repeated_word"
+	case CodeCapitalization:
+		return "capitalization"
+	case CodeTooManyErrors:
+		return "too_many_errors"
+	default:
+		return fmt.Sprintf("code_%d", int(c))
+	}
+}
+
+// Misspell is a misspelled word. Positions are counted in characters from
+// zero; Row and Col locate the word within its line.
+type Misspell struct {
+	Code ErrorCode `json:"code"`
+	Pos  int       `json:"pos"`
+	Row  int       `json:"row"`
+	Col  int       `json:"col"`
+	Len  int       `json:"len"`
+	Word string    `json:"word"`
+	// Suggestions are ordered from the most likely one.
+	Suggestions []string `json:"s"`
+}
+
+type SpellError struct {
+	Misspells []Misspell
+}
+
+func (e *SpellError) Error() string {
+	parts := make([]string, 0, len(e.Misspells))
+	for _, m := range e.Misspells {
+		part := fmt.Sprintf("at %d: %s", m.Pos+1, m.Word)
+		if m.Code != CodeUnknownWord && m.Code != 0 {
+			part += " (" + m.Code.String() + ")"
+		}
+		if len(m.Suggestions) > 0 {
+			part += ", did you mean " + strings.Join(m.Suggestions, ", ")
+		}
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, "; ")
+}
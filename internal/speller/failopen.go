@@ -0,0 +1,31 @@
+package speller
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bojackodin/notes/internal/log"
+)
+
+// FailOpenSpeller accepts texts unchecked, logging the failure, while the
+// speller it wraps is unavailable. It wraps any cache, so that unchecked
+// texts are not cached as correct.
+type FailOpenSpeller struct {
+	next Speller
+}
+
+func NewFailOpenSpeller(next Speller) *FailOpenSpeller {
+	return &FailOpenSpeller{
+		next: next,
+	}
+}
+
+func (f *FailOpenSpeller) Check(ctx context.Context, text string) error {
+	err := f.next.Check(ctx, text)
+	if errors.Is(err, ErrUnavailable) {
+		log.FromContext(ctx).Warn("spelling not checked", log.Err(err))
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,39 @@
+package speller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFailOpenSpeller(t *testing.T) {
+	spellErr := &SpellError{Misspells: []Misspell{{Word: "helo"}}}
+	otherErr := errors.New("other")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"correct", nil, nil},
+		{"misspelled", spellErr, spellErr},
+		{"unavailable", fmt.Errorf("%w: circuit open", ErrUnavailable), nil},
+		{"other error", otherErr, otherErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewFailOpenSpeller(spellerFunc(func(context.Context, string) error { return tt.err }))
+
+			if err := s.Check(context.Background(), "text"); err != tt.want {
+				t.Errorf("Check() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+type spellerFunc func(ctx context.Context, text string) error
+
+func (f spellerFunc) Check(ctx context.Context, text string) error {
+	return f(ctx, text)
+}
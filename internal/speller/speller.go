@@ -0,0 +1,21 @@
+// Package speller defines spell checking and the decorators shared by the
+// spellers, such as caching and failing open. The spellers themselves, e.g.
+// the Yandex.Speller client and the offline dictionary, live in their own
+// packages.
+package speller
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnavailable is returned when the spelling could not be checked, e.g.
+// because the service failed or rejected the request, or its circuit breaker
+// is open.
+var ErrUnavailable = errors.New("speller unavailable")
+
+// Speller checks the spelling of a text. It returns a *SpellError listing the
+// misspellings, if there are any.
+type Speller interface {
+	Check(ctx context.Context, text string) error
+}
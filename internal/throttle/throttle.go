@@ -0,0 +1,144 @@
+// Package throttle slows down repeated failures, such as password guessing,
+// with exponential backoff and temporary lockout.
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// FreeAttempts is the number of failures allowed before any delay.
+	FreeAttempts int
+	// BaseDelay is the delay after the first failure past the free ones. It
+	// doubles with every further failure up to MaxDelay, which must be set
+	// if BaseDelay is.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutThreshold is the number of failures after which the key is
+	// locked for LockoutDuration. Zero disables lockout.
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// Window is how long failures are remembered after the last one.
+	Window time.Duration
+}
+
+type entry struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+	// The state before the last attempt, restored if it is canceled.
+	prevLastFailure  time.Time
+	prevBlockedUntil time.Time
+}
+
+// Limiter tracks failures per key in memory.
+type Limiter struct {
+	cfg Config
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+func New(cfg Config) *Limiter {
+	return &Limiter{
+		cfg:     cfg,
+		now:     time.Now,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Attempt reports whether an attempt for the key may proceed and, if not, how
+// long to wait. An allowed attempt is counted as a failure right away, under
+// the same lock as the check, so that concurrent attempts cannot all pass
+// before any of them fails. Call Success or Cancel once the attempt turns out
+// not to have failed.
+func (l *Limiter) Attempt(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	e, ok := l.entries[key]
+	if ok && now.Before(e.blockedUntil) {
+		return e.blockedUntil.Sub(now), false
+	}
+	if !ok || now.Sub(e.lastFailure) > l.cfg.Window {
+		e = &entry{}
+		l.entries[key] = e
+	}
+
+	e.prevLastFailure, e.prevBlockedUntil = e.lastFailure, e.blockedUntil
+	e.failures++
+	e.lastFailure = now
+
+	if delay := l.delay(e.failures); delay > 0 {
+		e.blockedUntil = now.Add(delay)
+	}
+
+	return 0, true
+}
+
+// Cancel takes back an attempt that did not fail, keeping the earlier
+// failures of the key and the block they caused.
+func (l *Limiter) Cancel(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		return
+	}
+
+	e.failures--
+	if e.failures <= 0 {
+		delete(l.entries, key)
+		return
+	}
+
+	e.lastFailure, e.blockedUntil = e.prevLastFailure, e.prevBlockedUntil
+}
+
+// Success forgets the failures of the key.
+func (l *Limiter) Success(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key)
+}
+
+func (l *Limiter) delay(failures int) time.Duration {
+	if l.cfg.LockoutThreshold > 0 && failures >= l.cfg.LockoutThreshold {
+		return l.cfg.LockoutDuration
+	}
+
+	excess := failures - l.cfg.FreeAttempts
+	if excess <= 0 || l.cfg.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := l.cfg.BaseDelay
+	for i := 1; i < excess && delay < l.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, l.cfg.MaxDelay)
+}
+
+// sweep drops forgotten entries at most once per window, so that the map
+// does not grow with every key ever seen.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.cfg.Window {
+		return
+	}
+	l.lastSweep = now
+
+	for key, e := range l.entries {
+		if now.Sub(e.lastFailure) > l.cfg.Window && !now.Before(e.blockedUntil) {
+			delete(l.entries, key)
+		}
+	}
+}
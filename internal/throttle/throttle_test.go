@@ -0,0 +1,177 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestLimiter(cfg Config) (*Limiter, *time.Time) {
+	l := New(cfg)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	return l, &now
+}
+
+var testConfig = Config{
+	FreeAttempts:     2,
+	BaseDelay:        time.Second,
+	MaxDelay:         4 * time.Second,
+	LockoutThreshold: 8,
+	LockoutDuration:  time.Hour,
+	Window:           24 * time.Hour,
+}
+
+func TestLimiterFreeAttempts(t *testing.T) {
+	l, _ := newTestLimiter(testConfig)
+
+	for i := range testConfig.FreeAttempts {
+		if wait, ok := l.Attempt("key"); !ok {
+			t.Fatalf("attempt %d refused for %v, want allowed", i+1, wait)
+		}
+	}
+
+	// The first failure past the free ones delays the next attempt.
+	if _, ok := l.Attempt("key"); !ok {
+		t.Fatal("attempt past the free ones refused, want allowed")
+	}
+	if wait, ok := l.Attempt("key"); ok || wait != testConfig.BaseDelay {
+		t.Errorf("Attempt() = %v, %v, want refused for %v", wait, ok, testConfig.BaseDelay)
+	}
+
+	// Keys are counted apart.
+	if _, ok := l.Attempt("other"); !ok {
+		t.Error("attempt for another key refused, want allowed")
+	}
+}
+
+func TestLimiterBackoff(t *testing.T) {
+	l, now := newTestLimiter(testConfig)
+
+	// The delays after each failure: none for the free attempts, then
+	// doubling up to MaxDelay and the lockout at the threshold.
+	want := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second, 4 * time.Second, time.Hour}
+	for i, delay := range want {
+		if wait, ok := l.Attempt("key"); !ok {
+			t.Fatalf("attempt %d refused for %v, want allowed", i+1, wait)
+		}
+
+		wait, ok := l.Attempt("key")
+		if delay == 0 {
+			if !ok {
+				t.Fatalf("attempt after failure %d refused for %v, want allowed", i+1, wait)
+			}
+			l.Cancel("key")
+			continue
+		}
+		if ok || wait != delay {
+			t.Fatalf("attempt after failure %d = %v, %v, want refused for %v", i+1, wait, ok, delay)
+		}
+
+		*now = now.Add(delay)
+	}
+}
+
+func TestLimiterLockout(t *testing.T) {
+	cfg := Config{LockoutThreshold: 3, LockoutDuration: time.Hour, Window: 24 * time.Hour}
+	l, now := newTestLimiter(cfg)
+
+	for range cfg.LockoutThreshold {
+		if _, ok := l.Attempt("key"); !ok {
+			t.Fatal("attempt before the lockout refused, want allowed")
+		}
+	}
+
+	*now = now.Add(time.Hour - time.Second)
+	if wait, ok := l.Attempt("key"); ok || wait != time.Second {
+		t.Errorf("Attempt() = %v, %v, want refused for 1s", wait, ok)
+	}
+
+	*now = now.Add(time.Second)
+	if _, ok := l.Attempt("key"); !ok {
+		t.Error("attempt after the lockout refused, want allowed")
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	l, now := newTestLimiter(testConfig)
+
+	for range testConfig.FreeAttempts + 1 {
+		l.Attempt("key")
+	}
+	*now = now.Add(testConfig.BaseDelay)
+
+	// A canceled attempt neither adds a failure nor extends the block of the
+	// earlier ones, which has already run out.
+	for range 3 {
+		if wait, ok := l.Attempt("key"); !ok {
+			t.Fatalf("Attempt() refused for %v, want allowed", wait)
+		}
+		l.Cancel("key")
+	}
+
+	// The earlier failures are kept, so the next one doubles the delay.
+	l.Attempt("key")
+	if wait, ok := l.Attempt("key"); ok || wait != 2*testConfig.BaseDelay {
+		t.Errorf("Attempt() = %v, %v, want refused for %v", wait, ok, 2*testConfig.BaseDelay)
+	}
+
+	// Canceling the only attempt forgets the key.
+	l.Attempt("other")
+	l.Cancel("other")
+	if _, ok := l.entries["other"]; ok {
+		t.Error("entry kept after canceling the only attempt")
+	}
+}
+
+func TestLimiterSuccess(t *testing.T) {
+	l, _ := newTestLimiter(testConfig)
+
+	for range testConfig.FreeAttempts {
+		l.Attempt("key")
+	}
+	l.Success("key")
+
+	for range testConfig.FreeAttempts {
+		if _, ok := l.Attempt("key"); !ok {
+			t.Fatal("free attempt refused after a success, want allowed")
+		}
+	}
+}
+
+func TestLimiterWindow(t *testing.T) {
+	l, now := newTestLimiter(testConfig)
+
+	for range testConfig.FreeAttempts + 1 {
+		l.Attempt("key")
+	}
+
+	// Failures are remembered for the window after the last one.
+	*now = now.Add(testConfig.Window)
+	l.Attempt("key")
+	if _, ok := l.Attempt("key"); ok {
+		t.Fatal("attempt within the window allowed, want refused")
+	}
+
+	*now = now.Add(testConfig.Window + time.Second)
+	for range testConfig.FreeAttempts {
+		if _, ok := l.Attempt("key"); !ok {
+			t.Fatal("free attempt after the window refused, want allowed")
+		}
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	l, now := newTestLimiter(testConfig)
+
+	l.Attempt("old")
+	*now = now.Add(testConfig.Window + time.Second)
+	l.Attempt("new")
+
+	if _, ok := l.entries["old"]; ok {
+		t.Error("entry kept after the window")
+	}
+	if _, ok := l.entries["new"]; !ok {
+		t.Error("entry of the last attempt dropped")
+	}
+}
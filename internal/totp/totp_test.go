@@ -0,0 +1,87 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 secret of the RFC 6238 test vectors.
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+// The RFC 6238 appendix B vectors for SHA-1, truncated to the 6 digits used
+// here: the last digits of the 8 digit codes.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCode(t *testing.T) {
+	for _, tt := range rfcVectors {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.code {
+			t.Errorf("Code() at %d = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+
+	// Secrets are accepted in lower case, as some apps show them.
+	if got, err := Code(strings.ToLower(rfcSecret), Step(time.Unix(59, 0))); err != nil || got != "287082" {
+		t.Errorf("Code() of a lower case secret = %s, %v, want 287082", got, err)
+	}
+
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code() of an invalid secret error = nil, want error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	at := time.Unix(1111111109, 0)
+
+	tests := []struct {
+		name     string
+		code     string
+		t        time.Time
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", "081804", at, 0, Step(at), true},
+		{"previous step within skew", "081804", at.Add(Period), 1, Step(at), true},
+		{"next step within skew", "081804", at.Add(-Period), 1, Step(at), true},
+		{"outside skew", "081804", at.Add(2 * Period), 1, 0, false},
+		{"wrong code", "000000", at, 1, 0, false},
+		{"wrong length", "81804", at, 1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, tt.t, tt.skew)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate() = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Code(secret, 1); err != nil {
+		t.Errorf("Code() of a generated secret error = %v", err)
+	}
+	if other, _ := GenerateSecret(); other == secret {
+		t.Error("GenerateSecret() returned the same secret twice")
+	}
+}
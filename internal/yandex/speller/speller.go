@@ -3,49 +3,310 @@ package speller
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bojackodin/notes/internal/speller"
 )
 
-const serviceURL = "http://speller.yandex.net/services/spellservice.json/checkText"
+const (
+	serviceURL = "https://speller.yandex.net/services/spellservice.json/checkText"
+
+	// maxResponseSize bounds the response body read from the service.
+	maxResponseSize = 1 << 20
+
+	// maxTextLength is the longest text, in characters, the service checks
+	// in a single request. Longer texts are checked in parts.
+	maxTextLength = 10_000
+)
 
-type Speller interface {
-	Check(ctx context.Context, text string) error
+// YandexSpeller checks spelling with the Yandex.Speller API. Failed requests
+// are retried with exponential backoff and jitter, and repeated failures open
+// a circuit breaker that fails fast until the service recovers.
+type YandexSpeller struct {
+	client  *http.Client
+	breaker *breaker
+	options *options
 }
 
-type YandexSpeller struct{}
+func NewYandexSpeller(optFns ...OptionFn) *YandexSpeller {
+	options := &options{
+		baseURL:          serviceURL,
+		connectTimeout:   2 * time.Second,
+		timeout:          5 * time.Second,
+		retries:          2,
+		retryBaseDelay:   100 * time.Millisecond,
+		retryMaxDelay:    time.Second,
+		breakerThreshold: 5,
+		breakerCooldown:  30 * time.Second,
+	}
+	for _, fn := range optFns {
+		fn(options)
+	}
+
+	client := options.client
+	if client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = (&net.Dialer{Timeout: options.connectTimeout}).DialContext
+		transport.TLSHandshakeTimeout = options.connectTimeout
+
+		client = &http.Client{
+			Transport: transport,
+			Timeout:   options.timeout,
+		}
+	}
 
-func NewYandexSpeller() *YandexSpeller {
-	return &YandexSpeller{}
+	return &YandexSpeller{
+		client:  client,
+		breaker: newBreaker(options.breakerThreshold, options.breakerCooldown),
+		options: options,
+	}
 }
 
-type Misspell struct {
-	Pos  int    `json:"pos"`
-	Word string `json:"word"`
+type options struct {
+	client           *http.Client
+	baseURL          string
+	connectTimeout   time.Duration
+	timeout          time.Duration
+	retries          int
+	retryBaseDelay   time.Duration
+	retryMaxDelay    time.Duration
+	breakerThreshold int
+	breakerCooldown  time.Duration
 }
 
-func (y *YandexSpeller) Check(ctx context.Context, text string) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, serviceURL, nil)
-	if err != nil {
-		return err
+type OptionFn func(*options)
+
+// WithHTTPClient replaces the client built from the timeouts.
+func WithHTTPClient(client *http.Client) OptionFn {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithBaseURL sets the URL of the checkText method.
+func WithBaseURL(baseURL string) OptionFn {
+	return func(o *options) {
+		o.baseURL = baseURL
 	}
+}
 
-	q := req.URL.Query()
-	q.Add("text", text)
-	req.URL.RawQuery = q.Encode()
+// WithTimeouts sets the timeout of establishing a connection and the one of
+// a whole request attempt. Zero values keep the defaults.
+func WithTimeouts(connect, request time.Duration) OptionFn {
+	return func(o *options) {
+		if connect > 0 {
+			o.connectTimeout = connect
+		}
+		if request > 0 {
+			o.timeout = request
+		}
+	}
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
+// WithRetries sets the number of retries of requests failed by network errors
+// or 429 and 5xx responses. The delay before a retry is random, up to
+// baseDelay doubled with every retry and capped by maxDelay. Zero values keep
+// the defaults.
+func WithRetries(retries int, baseDelay, maxDelay time.Duration) OptionFn {
+	return func(o *options) {
+		if retries > 0 {
+			o.retries = retries
+		}
+		if baseDelay > 0 {
+			o.retryBaseDelay = baseDelay
+		}
+		if maxDelay > 0 {
+			o.retryMaxDelay = maxDelay
+		}
 	}
+}
+
+// WithCircuitBreaker opens the circuit after threshold consecutive failed
+// checks for cooldown. Zero values keep the defaults.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) OptionFn {
+	return func(o *options) {
+		if threshold > 0 {
+			o.breakerThreshold = threshold
+		}
+		if cooldown > 0 {
+			o.breakerCooldown = cooldown
+		}
+	}
+}
 
-	var misspells []Misspell
-	if err = json.NewDecoder(resp.Body).Decode(&misspells); err != nil {
-		return err
+func (y *YandexSpeller) Check(ctx context.Context, text string) error {
+	var misspells []speller.Misspell
+	for _, c := range split(text, maxTextLength) {
+		found, err := y.check(ctx, c.text)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range found {
+			misspells = append(misspells, c.locate(m))
+		}
 	}
 
 	if len(misspells) > 0 {
-		return &SpellError{misspells}
+		return &speller.SpellError{Misspells: misspells}
 	}
 
 	return nil
 }
+
+func (y *YandexSpeller) check(ctx context.Context, text string) ([]speller.Misspell, error) {
+	if !y.breaker.allow() {
+		return nil, fmt.Errorf("%w: circuit open", speller.ErrUnavailable)
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		var (
+			misspells []speller.Misspell
+			transient bool
+		)
+		misspells, transient, err = y.request(ctx, text)
+		if err == nil {
+			y.breaker.success()
+			return misspells, nil
+		}
+		if ctx.Err() != nil {
+			y.breaker.cancel()
+			return nil, ctx.Err()
+		}
+		if !transient {
+			// The service is up but cannot handle the request, which is no
+			// reason to stop sending it others.
+			y.breaker.cancel()
+			return nil, fmt.Errorf("%w: %v", speller.ErrUnavailable, err)
+		}
+		if attempt == y.options.retries {
+			break
+		}
+
+		timer := time.NewTimer(y.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			y.breaker.cancel()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	y.breaker.failure()
+	return nil, fmt.Errorf("%w: %v", speller.ErrUnavailable, err)
+}
+
+// request makes a single attempt and reports whether a failure is a
+// transient one of the service: a network error or a 429 or 5xx response.
+// Only those are retried and counted by the circuit breaker.
+func (y *YandexSpeller) request(ctx context.Context, text string) ([]speller.Misspell, bool, error) {
+	form := url.Values{"text": {text}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, y.options.baseURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := y.client.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer func() {
+		// Drain the body, so that the connection is reused.
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+		resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		transient := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		return nil, transient, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var misspells []speller.Misspell
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&misspells); err != nil {
+		return nil, false, fmt.Errorf("decode response: %w", err)
+	}
+
+	return misspells, false, nil
+}
+
+// backoff returns a random delay up to the exponential one of the attempt.
+func (y *YandexSpeller) backoff(attempt int) time.Duration {
+	delay := y.options.retryMaxDelay
+	if attempt < 32 {
+		delay = min(y.options.retryBaseDelay<<attempt, y.options.retryMaxDelay)
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	return rand.N(delay + 1)
+}
+
+// chunk is a part of a text checked in a separate request, along with the
+// position of its start within the text.
+type chunk struct {
+	text          string
+	pos, row, col int
+}
+
+// locate moves a misspelling found in the chunk to its position within the
+// whole text.
+func (c chunk) locate(m speller.Misspell) speller.Misspell {
+	if m.Row == 0 {
+		m.Col += c.col
+	}
+	m.Pos += c.pos
+	m.Row += c.row
+
+	return m
+}
+
+// split cuts the text into chunks of at most size characters, after the last
+// whitespace within the size when there is one, so that words stay whole.
+func split(text string, size int) []chunk {
+	var (
+		chunks []chunk
+		c      chunk
+	)
+	for utf8.RuneCountInString(text) > size {
+		end := 0
+		for range size {
+			_, n := utf8.DecodeRuneInString(text[end:])
+			end += n
+		}
+		if i := strings.LastIndexFunc(text[:end], unicode.IsSpace); i > 0 {
+			_, n := utf8.DecodeRuneInString(text[i:])
+			end = i + n
+		}
+
+		c.text = text[:end]
+		chunks = append(chunks, c)
+
+		runes := utf8.RuneCountInString(c.text)
+		if i := strings.LastIndexByte(c.text, '\n'); i >= 0 {
+			c.row += strings.Count(c.text, "\n")
+			c.col = utf8.RuneCountInString(c.text[i+1:])
+		} else {
+			c.col += runes
+		}
+		c.pos += runes
+
+		text = text[end:]
+	}
+
+	c.text = text
+	return append(chunks, c)
+}
@@ -0,0 +1,438 @@
+package speller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bojackodin/notes/internal/speller"
+)
+
+// testServer counts the requests and answers them with the handler.
+type testServer struct {
+	*httptest.Server
+	requests atomic.Int64
+}
+
+func newTestServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, n int64)) *testServer {
+	t.Helper()
+
+	s := &testServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r, s.requests.Add(1))
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func newTestSpeller(url string, optFns ...OptionFn) *YandexSpeller {
+	return NewYandexSpeller(append([]OptionFn{
+		WithBaseURL(url),
+		WithTimeouts(time.Second, 100*time.Millisecond),
+		WithRetries(2, time.Millisecond, 5*time.Millisecond),
+	}, optFns...)...)
+}
+
+const misspellsJSON = `[{"code":1,"pos":0,"row":0,"col":0,"len":5,"word":"helo","s":["hello"]}]`
+
+// statuses answers the requests with the statuses in turn, the last one
+// repeated, and an empty list of misspellings on 200 OK.
+func statuses(codes ...int) func(http.ResponseWriter, *http.Request, int64) {
+	return func(w http.ResponseWriter, r *http.Request, n int64) {
+		code := codes[min(int(n), len(codes))-1]
+		if code != http.StatusOK {
+			w.WriteHeader(code)
+			return
+		}
+		fmt.Fprint(w, "[]")
+	}
+}
+
+// hang blocks the request until the client gives up. The body is read
+// first, so that the server notices the closed connection.
+func hang(r *http.Request) {
+	_ = r.ParseForm()
+	select {
+	case <-r.Context().Done():
+	case <-time.After(5 * time.Second):
+	}
+}
+
+func TestYandexSpellerCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		handler      func(http.ResponseWriter, *http.Request, int64)
+		wantErr      error
+		wantMisspell bool
+		wantRequests int64
+	}{
+		{
+			name: "correct",
+			handler: func(w http.ResponseWriter, r *http.Request, _ int64) {
+				if r.Method != http.MethodPost || r.FormValue("text") != "helo world" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				fmt.Fprint(w, "[]")
+			},
+			wantRequests: 1,
+		},
+		{
+			name: "misspelled",
+			handler: func(w http.ResponseWriter, _ *http.Request, _ int64) {
+				fmt.Fprint(w, misspellsJSON)
+			},
+			wantMisspell: true,
+			wantRequests: 1,
+		},
+		{
+			name:         "recovers after 5xx",
+			handler:      statuses(http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK),
+			wantRequests: 3,
+		},
+		{
+			name:         "recovers after 429",
+			handler:      statuses(http.StatusTooManyRequests, http.StatusOK),
+			wantRequests: 2,
+		},
+		{
+			name:         "5xx",
+			handler:      statuses(http.StatusInternalServerError),
+			wantErr:      speller.ErrUnavailable,
+			wantRequests: 3,
+		},
+		{
+			name:         "4xx not retried",
+			handler:      statuses(http.StatusBadRequest),
+			wantErr:      speller.ErrUnavailable,
+			wantRequests: 1,
+		},
+		{
+			name: "invalid response not retried",
+			handler: func(w http.ResponseWriter, _ *http.Request, _ int64) {
+				fmt.Fprint(w, "{")
+			},
+			wantErr:      speller.ErrUnavailable,
+			wantRequests: 1,
+		},
+		{
+			name: "timeout",
+			handler: func(_ http.ResponseWriter, r *http.Request, _ int64) {
+				hang(r)
+			},
+			wantErr:      speller.ErrUnavailable,
+			wantRequests: 3,
+		},
+		{
+			name: "recovers after timeout",
+			handler: func(w http.ResponseWriter, r *http.Request, n int64) {
+				if n == 1 {
+					hang(r)
+					return
+				}
+				fmt.Fprint(w, "[]")
+			},
+			wantRequests: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, tt.handler)
+			s := newTestSpeller(server.URL)
+
+			err := s.Check(context.Background(), "helo world")
+
+			var spellErr *speller.SpellError
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Check() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantMisspell:
+				if !errors.As(err, &spellErr) || len(spellErr.Misspells) != 1 || spellErr.Misspells[0].Suggestions[0] != "hello" {
+					t.Errorf("Check() error = %v, want the misspelling of helo", err)
+				}
+			case err != nil:
+				t.Errorf("Check() error = %v, want nil", err)
+			}
+
+			if got := server.requests.Load(); got != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestYandexSpellerCheckCanceled(t *testing.T) {
+	server := newTestServer(t, statuses(http.StatusServiceUnavailable))
+	s := newTestSpeller(server.URL,
+		WithRetries(5, time.Hour, time.Hour),
+		WithCircuitBreaker(1, time.Hour),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := s.Check(ctx, "text"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Check() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// A canceled check is not a failure of the service.
+	if !s.breaker.allow() {
+		t.Error("breaker opened by a canceled check")
+	}
+}
+
+func TestYandexSpellerCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	server := newTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int64) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, "[]")
+	})
+
+	const cooldown = time.Minute
+	s := newTestSpeller(server.URL,
+		WithRetries(1, time.Millisecond, time.Millisecond),
+		WithCircuitBreaker(2, cooldown),
+	)
+
+	now := time.Now()
+	s.breaker.now = func() time.Time { return now }
+
+	steps := []struct {
+		name         string
+		advance      time.Duration
+		healthy      bool
+		wantErr      bool
+		wantRequests int64
+	}{
+		{name: "closed, first failure", wantErr: true, wantRequests: 2},
+		{name: "closed, second failure opens", wantErr: true, wantRequests: 2},
+		{name: "open", wantErr: true, wantRequests: 0},
+		{name: "open, recovered before cooldown", advance: cooldown - time.Second, healthy: true, wantErr: true, wantRequests: 0},
+		{name: "half-open, failed trial reopens", advance: time.Second, wantErr: true, wantRequests: 2},
+		{name: "reopened", advance: cooldown - time.Second, healthy: true, wantErr: true, wantRequests: 0},
+		{name: "half-open, successful trial closes", advance: time.Second, healthy: true, wantRequests: 1},
+		{name: "closed", healthy: true, wantRequests: 1},
+		{name: "closed, failures counted from zero", wantErr: true, wantRequests: 2},
+		{name: "still closed", healthy: true, wantRequests: 1},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		healthy.Store(step.healthy)
+		before := server.requests.Load()
+
+		err := s.Check(context.Background(), "text")
+		if step.wantErr && !errors.Is(err, speller.ErrUnavailable) {
+			t.Errorf("%s: Check() error = %v, want %v", step.name, err, speller.ErrUnavailable)
+		}
+		if !step.wantErr && err != nil {
+			t.Errorf("%s: Check() error = %v, want nil", step.name, err)
+		}
+
+		if got := server.requests.Load() - before; got != step.wantRequests {
+			t.Errorf("%s: got %d requests, want %d", step.name, got, step.wantRequests)
+		}
+	}
+}
+
+func TestYandexSpellerRequestErrorsKeepCircuitClosed(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, _ *http.Request, n int64) {
+		switch n {
+		case 1:
+			w.WriteHeader(http.StatusBadRequest)
+		case 2:
+			fmt.Fprint(w, "{")
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	s := newTestSpeller(server.URL,
+		WithRetries(1, time.Millisecond, time.Millisecond),
+		WithCircuitBreaker(1, time.Hour),
+	)
+
+	// A rejected request and an invalid response are not failures of the
+	// service, so they leave the circuit closed for the next checks.
+	for range 3 {
+		if err := s.Check(context.Background(), "text"); !errors.Is(err, speller.ErrUnavailable) {
+			t.Errorf("Check() error = %v, want %v", err, speller.ErrUnavailable)
+		}
+	}
+	if got := server.requests.Load(); got != 4 {
+		t.Errorf("got %d requests, want 4", got)
+	}
+
+	if s.breaker.allow() {
+		t.Error("breaker closed after a 5xx response")
+	}
+}
+
+func TestYandexSpellerCheckLongText(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		longest int
+	)
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int64) {
+		text := r.FormValue("text")
+
+		mu.Lock()
+		longest = max(longest, len([]rune(text)))
+		mu.Unlock()
+
+		_ = json.NewEncoder(w).Encode(findHelo(text))
+	})
+	s := newTestSpeller(server.URL)
+
+	var b strings.Builder
+	for i := 0; b.Len() < 5*maxTextLength; i++ {
+		switch i % 3 {
+		case 0:
+			b.WriteString("ёжик helo ")
+		case 1:
+			b.WriteString("world helo\n")
+		default:
+			b.WriteString(strings.Repeat("я", i%50) + " ")
+		}
+	}
+	text := b.String()
+
+	var spellErr *speller.SpellError
+	if err := s.Check(context.Background(), text); !errors.As(err, &spellErr) {
+		t.Fatalf("Check() error = %v, want *speller.SpellError", err)
+	}
+
+	if want := findHelo(text); !slices.EqualFunc(spellErr.Misspells, want, func(a, b speller.Misspell) bool {
+		return a.Pos == b.Pos && a.Row == b.Row && a.Col == b.Col && a.Word == b.Word
+	}) {
+		t.Errorf("Check() found %d misspellings, want the %d of the whole text at the same positions", len(spellErr.Misspells), len(want))
+	}
+	if longest > maxTextLength {
+		t.Errorf("checked a text of %d characters, want at most %d", longest, maxTextLength)
+	}
+	if got := server.requests.Load(); got < 2 {
+		t.Errorf("got %d requests, want the text split", got)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		text string
+		want []chunk
+	}{
+		{"", []chunk{{text: ""}}},
+		{"ab cd", []chunk{{text: "ab cd"}}},
+		{"ab cdef", []chunk{{text: "ab "}, {text: "cdef", pos: 3, col: 3}}},
+		{"abcdefgh", []chunk{{text: "abcde"}, {text: "fgh", pos: 5, col: 5}}},
+		{"ё\nжз ик", []chunk{{text: "ё\nжз "}, {text: "ик", pos: 5, row: 1, col: 3}}},
+	}
+	for _, tt := range tests {
+		if got := split(tt.text, 5); !slices.Equal(got, tt.want) {
+			t.Errorf("split(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+// findHelo reports every helo in the text, as the service would.
+func findHelo(text string) []speller.Misspell {
+	var (
+		misspells []speller.Misspell
+		lineStart int
+	)
+	for row, line := range strings.Split(text, "\n") {
+		runes := []rune(line)
+		for col := 0; col+4 <= len(runes); col++ {
+			if string(runes[col:col+4]) == "helo" {
+				misspells = append(misspells, speller.Misspell{Code: speller.CodeUnknownWord, Pos: lineStart + col, Row: row, Col: col, Len: 4, Word: "helo"})
+			}
+		}
+		lineStart += len(runes) + 1
+	}
+
+	return misspells
+}
+
+func TestBreakerSingleTrial(t *testing.T) {
+	b := newBreaker(1, time.Minute)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	b.failure()
+	now = now.Add(time.Minute)
+
+	var (
+		wg      sync.WaitGroup
+		allowed atomic.Int64
+	)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if b.allow() {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != 1 {
+		t.Errorf("half-open breaker allowed %d calls, want 1", got)
+	}
+
+	// A canceled trial lets the next call try again.
+	b.cancel()
+	if !b.allow() {
+		t.Error("allow() = false after a canceled trial, want true")
+	}
+}
+
+func TestYandexSpellerBackoff(t *testing.T) {
+	s := NewYandexSpeller(WithRetries(10, 100*time.Millisecond, time.Second))
+
+	for attempt := range 40 {
+		limit := time.Second
+		if attempt < 4 {
+			limit = 100 * time.Millisecond << attempt
+		}
+
+		for range 100 {
+			if d := s.backoff(attempt); d < 0 || d > limit {
+				t.Fatalf("backoff(%d) = %v, want within [0, %v]", attempt, d, limit)
+			}
+		}
+	}
+}
+
+func TestNewYandexSpellerDefaults(t *testing.T) {
+	defaults := NewYandexSpeller().options
+	s := NewYandexSpeller(WithTimeouts(0, 0), WithRetries(0, 0, 0), WithCircuitBreaker(0, 0))
+
+	if *s.options != *defaults {
+		t.Errorf("options = %+v, want the defaults %+v", *s.options, *defaults)
+	}
+	if s.client.Timeout != defaults.timeout || s.client.Timeout == 0 {
+		t.Errorf("client timeout = %v, want %v", s.client.Timeout, defaults.timeout)
+	}
+}
+
+func TestFailOpenSpellerServer(t *testing.T) {
+	server := newTestServer(t, statuses(http.StatusServiceUnavailable))
+	s := speller.NewFailOpenSpeller(newTestSpeller(server.URL))
+
+	if err := s.Check(context.Background(), "text"); err != nil {
+		t.Errorf("Check() error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,4 @@
+ALTER TABLE notes
+    ADD COLUMN IF NOT EXISTS body text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS created_at timestamp NOT NULL DEFAULT NOW(),
+    ADD COLUMN IF NOT EXISTS updated_at timestamp NOT NULL DEFAULT NOW();
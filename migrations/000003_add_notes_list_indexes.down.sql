@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS notes_user_id_title_idx;
+DROP INDEX IF EXISTS notes_user_id_updated_at_idx;
+DROP INDEX IF EXISTS notes_user_id_created_at_idx;
+DROP INDEX IF EXISTS notes_user_id_id_idx;
@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS notes_user_id_id_idx ON notes (user_id, id);
+CREATE INDEX IF NOT EXISTS notes_user_id_created_at_idx ON notes (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS notes_user_id_updated_at_idx ON notes (user_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS notes_user_id_title_idx ON notes (user_id, title, id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS notes_notebook_id_idx;
+
+ALTER TABLE notes DROP COLUMN IF EXISTS notebook_id;
+
+DROP TABLE IF EXISTS notebooks;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS notebooks (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    parent_id bigint REFERENCES notebooks (id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    updated_at timestamp NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS notebooks_user_id_parent_id_idx ON notebooks (user_id, parent_id);
+
+ALTER TABLE notes
+    ADD COLUMN IF NOT EXISTS notebook_id bigint REFERENCES notebooks (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS notes_notebook_id_idx ON notes (notebook_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS note_revisions;
+
+DROP FUNCTION IF EXISTS note_revisions_immutable();
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS note_revisions (
+    note_id bigint NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+    revision integer NOT NULL,
+    title text NOT NULL,
+    body text NOT NULL,
+    tags text[] NOT NULL DEFAULT '{}',
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (note_id, revision)
+);
+
+CREATE OR REPLACE FUNCTION note_revisions_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'note revisions are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER note_revisions_no_update
+    BEFORE UPDATE ON note_revisions
+    FOR EACH ROW EXECUTE FUNCTION note_revisions_immutable();
+
+INSERT INTO note_revisions (note_id, revision, title, body, tags, created_at)
+SELECT notes.id, 1, notes.title, notes.body,
+    ARRAY(
+        SELECT tags.name
+        FROM note_tags JOIN tags ON tags.id = note_tags.tag_id
+        WHERE note_tags.note_id = notes.id
+        ORDER BY tags.name
+    ),
+    notes.updated_at
+FROM notes
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id varchar(32) PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    expires_at timestamp NOT NULL,
+    revoked_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_hash bytea PRIMARY KEY,
+    session_id varchar(32) NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    used_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_session_id_idx ON refresh_tokens (session_id);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS two_factor (
+    user_id bigint PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret varchar(64) NOT NULL,
+    last_step bigint NOT NULL DEFAULT 0,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    confirmed_at timestamp
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    user_id bigint NOT NULL REFERENCES two_factor (user_id) ON DELETE CASCADE,
+    code_hash bytea NOT NULL,
+    used_at timestamp,
+    PRIMARY KEY (user_id, code_hash)
+);